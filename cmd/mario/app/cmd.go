@@ -31,7 +31,7 @@ func NewCommand() *cobra.Command {
 				klog.Fatalf("can't parse options to config: %v", err)
 			}
 
-			sig := make(chan os.Signal)
+			sig := make(chan os.Signal, 1)
 			signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
 
 			stopCh := make(chan struct{})
@@ -50,6 +50,8 @@ func NewCommand() *cobra.Command {
 	opts.AddFlags(cmd.Flags())
 
 	cmd.AddCommand(NewVersionCmd())
+	cmd.AddCommand(NewLintCmd())
+	cmd.AddCommand(NewRunCmd())
 
 	return cmd
 }
@@ -17,3 +17,25 @@ type Config struct {
 
 	Token string
 }
+
+// LintConfig defines config of lint command
+type LintConfig struct {
+	MarioFile   string
+	PipeFile    string
+	ActionFiles []string
+	Namespace   string
+
+	Output string
+}
+
+// RunConfig defines config of run command
+type RunConfig struct {
+	MarioFile   string
+	PipeFile    string
+	ActionFiles []string
+	Namespace   string
+
+	Dir     string
+	Ref     string
+	Version string
+}
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"k8s.io/klog"
+
+	"github.com/liubog2008/oooops/cmd/mario/app/config"
+	"github.com/liubog2008/oooops/cmd/mario/app/options"
+	"github.com/liubog2008/oooops/pkg/mario/lint"
+)
+
+// NewLintCmd returns lint command
+func NewLintCmd() *cobra.Command {
+	opts := options.NewLintOptions()
+	cmd := &cobra.Command{
+		Use:  "lint",
+		Long: "lint checks mario file offline, it exits with non-zero code if any problem is found",
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := opts.Config()
+			if err != nil {
+				klog.Fatalf("can't parse options to config: %v", err)
+			}
+
+			n, err := RunLint(cfg, os.Stdout)
+			if err != nil {
+				klog.Fatalf("lint failed: %v", err)
+			}
+			if n != 0 {
+				os.Exit(1)
+			}
+		},
+	}
+
+	opts.AddFlags(cmd.Flags())
+
+	return cmd
+}
+
+// RunLint lints files and writes problems to out, it returns number of problems
+func RunLint(cfg *config.LintConfig, out io.Writer) (int, error) {
+	c := lint.Config{
+		MarioFile:   cfg.MarioFile,
+		PipeFile:    cfg.PipeFile,
+		ActionFiles: cfg.ActionFiles,
+		Namespace:   cfg.Namespace,
+	}
+	issues, err := lint.Lint(&c)
+	if err != nil {
+		return 0, err
+	}
+
+	if cfg.Output == options.OutputJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(issues); err != nil {
+			return 0, err
+		}
+		return len(issues), nil
+	}
+
+	for _, issue := range issues {
+		if issue.Field == "" {
+			fmt.Fprintf(out, "%s: %s\n", issue.File, issue.Message)
+			continue
+		}
+		fmt.Fprintf(out, "%s: %s: %s\n", issue.File, issue.Field, issue.Message)
+	}
+	return len(issues), nil
+}
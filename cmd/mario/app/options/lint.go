@@ -0,0 +1,66 @@
+package options
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+
+	"github.com/liubog2008/oooops/cmd/mario/app/config"
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+const (
+	// OutputText defines human readable output of lint
+	OutputText = "text"
+	// OutputJSON defines json output of lint
+	OutputJSON = "json"
+)
+
+// LintOptions defines options of lint command
+type LintOptions struct {
+	MarioFile   string
+	PipeFile    string
+	ActionFiles []string
+	Namespace   string
+
+	Output string
+}
+
+// NewLintOptions returns new lint options
+func NewLintOptions() *LintOptions {
+	return &LintOptions{
+		MarioFile: v1alpha1.MarioFile,
+		Namespace: "default",
+		Output:    OutputText,
+	}
+}
+
+// AddFlags adds flags for lint options
+func (opt *LintOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&opt.MarioFile, "file", "f", opt.MarioFile, "path of mario file")
+	fs.StringVar(&opt.PipeFile, "pipe", opt.PipeFile, "path of pipe whose stages reference actions of mario")
+	fs.StringSliceVar(&opt.ActionFiles, "action", opt.ActionFiles,
+		"paths of actions which can be imported, imports are not resolved if it is unset")
+	fs.StringVarP(&opt.Namespace, "namespace", "n", opt.Namespace, "namespace of mario to resolve imports")
+	fs.StringVarP(&opt.Output, "output", "o", opt.Output, "output format, one of text and json")
+}
+
+// Config parses lint options to config
+func (opt *LintOptions) Config() (*config.LintConfig, error) {
+	switch opt.Output {
+	case OutputText, OutputJSON:
+	default:
+		return nil, fmt.Errorf("unsupported output format %s", opt.Output)
+	}
+
+	c := &config.LintConfig{
+		MarioFile:   opt.MarioFile,
+		PipeFile:    opt.PipeFile,
+		ActionFiles: opt.ActionFiles,
+		Namespace:   opt.Namespace,
+
+		Output: opt.Output,
+	}
+
+	return c, nil
+}
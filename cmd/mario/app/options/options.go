@@ -1,7 +1,10 @@
 package options
 
 import (
+	"fmt"
+	"io/ioutil"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/spf13/pflag"
@@ -18,7 +21,7 @@ type Options struct {
 	Addr                    string
 	GracefulShutdownTimeout time.Duration
 
-	Token string
+	TokenFile string
 }
 
 // NewOptions returns new running options
@@ -45,8 +48,7 @@ func (opt *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&opt.Remote, "remote", opt.Remote, "remote url of git repo")
 	fs.StringVar(&opt.Ref, "ref", opt.Ref, "ref of git repo")
 
-	// TODO(liubog2008): set token which should not be seen in kubernetes yaml
-	fs.StringVar(&opt.Token, "token", opt.Token, "token of mario file")
+	fs.StringVar(&opt.TokenFile, "token-file", opt.TokenFile, "path of file which contains token of mario file")
 }
 
 // Config parse options to config
@@ -60,6 +62,18 @@ func (opt *Options) Config() (*config.Config, error) {
 		return nil, err
 	}
 
+	if opt.TokenFile == "" {
+		return nil, fmt.Errorf("token file is required")
+	}
+	b, err := ioutil.ReadFile(opt.TokenFile)
+	if err != nil {
+		return nil, err
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return nil, fmt.Errorf("token file %s is empty", opt.TokenFile)
+	}
+
 	c := &config.Config{
 		GitCommand: gitCmd,
 
@@ -69,7 +83,7 @@ func (opt *Options) Config() (*config.Config, error) {
 		Addr:                    opt.Addr,
 		GracefulShutdownTimeout: opt.GracefulShutdownTimeout,
 
-		Token: opt.Token,
+		Token: token,
 	}
 
 	return c, nil
@@ -0,0 +1,79 @@
+package options
+
+import (
+	"os"
+
+	"github.com/spf13/pflag"
+
+	"github.com/liubog2008/oooops/cmd/mario/app/config"
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	"github.com/liubog2008/oooops/pkg/mario/git"
+)
+
+// RunOptions defines options of run command
+type RunOptions struct {
+	MarioFile   string
+	PipeFile    string
+	ActionFiles []string
+	Namespace   string
+
+	Ref     string
+	Version string
+}
+
+// NewRunOptions returns new run options
+func NewRunOptions() *RunOptions {
+	return &RunOptions{
+		MarioFile: v1alpha1.MarioFile,
+		Namespace: "default",
+	}
+}
+
+// AddFlags adds flags for run options
+func (opt *RunOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&opt.MarioFile, "file", "f", opt.MarioFile, "path of mario file")
+	fs.StringVar(&opt.PipeFile, "stages", opt.PipeFile, "path of pipe whose stages will be run in order of dependencies")
+	fs.StringSliceVar(&opt.ActionFiles, "action", opt.ActionFiles, "paths of actions which can be imported")
+	fs.StringVarP(&opt.Namespace, "namespace", "n", opt.Namespace, "namespace of mario to resolve imports")
+	fs.StringVar(&opt.Ref, "ref", opt.Ref, "git ref to match conditions of stages, it defaults to the current git ref")
+	fs.StringVar(&opt.Version, "version", opt.Version, "value of version env, it defaults to ref")
+}
+
+// Config parses run options to config
+func (opt *RunOptions) Config() (*config.RunConfig, error) {
+	w, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	ref := opt.Ref
+	if ref == "" {
+		gitCmd, err := git.New(w)
+		if err != nil {
+			return nil, err
+		}
+		ref, err = gitCmd.Ref()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// version env of action job is also the ref of flow
+	version := opt.Version
+	if version == "" {
+		version = ref
+	}
+
+	c := &config.RunConfig{
+		MarioFile:   opt.MarioFile,
+		PipeFile:    opt.PipeFile,
+		ActionFiles: opt.ActionFiles,
+		Namespace:   opt.Namespace,
+
+		Dir:     w,
+		Ref:     ref,
+		Version: version,
+	}
+
+	return c, nil
+}
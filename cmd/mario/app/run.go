@@ -0,0 +1,81 @@
+package app
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"k8s.io/klog"
+
+	"github.com/liubog2008/oooops/cmd/mario/app/config"
+	"github.com/liubog2008/oooops/cmd/mario/app/options"
+	"github.com/liubog2008/oooops/pkg/mario/runner"
+)
+
+// NewRunCmd returns run command
+func NewRunCmd() *cobra.Command {
+	opts := options.NewRunOptions()
+	cmd := &cobra.Command{
+		Use:  "run [action]",
+		Long: "run runs an action or stages of a pipe as local processes in root dir of git project",
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if (len(args) == 0) == (opts.PipeFile == "") {
+				klog.Fatalf("one of action and --stages must be specified")
+			}
+
+			cfg, err := opts.Config()
+			if err != nil {
+				klog.Fatalf("can't parse options to config: %v", err)
+			}
+
+			sig := make(chan os.Signal, 1)
+			signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+
+			stopCh := make(chan struct{})
+
+			go func() {
+				<-sig
+				close(stopCh)
+			}()
+
+			action := ""
+			if len(args) != 0 {
+				action = args[0]
+			}
+
+			if err := RunActions(cfg, action, stopCh); err != nil {
+				klog.Fatalf("run failed: %v", err)
+			}
+		},
+	}
+
+	opts.AddFlags(cmd.Flags())
+
+	return cmd
+}
+
+// RunActions runs the action, stages of pipe are run if action is empty
+func RunActions(cfg *config.RunConfig, action string, stopCh <-chan struct{}) error {
+	c := runner.Config{
+		MarioFile:   cfg.MarioFile,
+		PipeFile:    cfg.PipeFile,
+		ActionFiles: cfg.ActionFiles,
+		Namespace:   cfg.Namespace,
+		Dir:         cfg.Dir,
+		Ref:         cfg.Ref,
+		Version:     cfg.Version,
+		Stdout:      os.Stdout,
+		Stderr:      os.Stderr,
+	}
+	r, err := runner.New(&c)
+	if err != nil {
+		return err
+	}
+
+	if action == "" {
+		return r.RunStages(stopCh)
+	}
+	return r.RunAction(stopCh, action)
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/liubog2008/oooops/cmd/operator/app/config"
 	"github.com/liubog2008/oooops/cmd/operator/app/options"
+	"github.com/liubog2008/oooops/pkg/admission"
 	"github.com/liubog2008/oooops/pkg/controller/flow"
 	"github.com/liubog2008/oooops/pkg/controller/pipe"
 	"github.com/liubog2008/oooops/pkg/version"
@@ -66,6 +67,10 @@ func Run(cfg *config.Config, stopCh chan struct{}) error {
 		EventInformer: cfg.EventInformer,
 		PipeInformer:  cfg.PipeInformer,
 		FlowInformer:  cfg.FlowInformer,
+
+		EventTTL: cfg.EventTTL,
+
+		Defaulter: cfg.Defaulter,
 	})
 
 	fc := flow.NewController(&flow.ControllerOptions{
@@ -73,19 +78,41 @@ func Run(cfg *config.Config, stopCh chan struct{}) error {
 		ExtClient:  cfg.ExtClient,
 
 		FlowInformer:      cfg.FlowInformer,
+		ActionInformer:    cfg.ActionInformer,
 		JobInformer:       cfg.JobInformer,
 		PVCInformer:       cfg.PVCInformer,
 		ConfigMapInformer: cfg.ConfigMapInformer,
+		SecretInformer:    cfg.SecretInformer,
 		PodInformer:       cfg.PodInformer,
+
+		Defaulter: cfg.Defaulter,
+
+		PodPolicies: cfg.PodPolicies,
 	})
 
 	go cfg.KubeInformerFactory.Start(stopCh)
 	go cfg.PodInformerFactory.Start(stopCh)
+	go cfg.SecretInformerFactory.Start(stopCh)
 	go cfg.ExtInformerFactory.Start(stopCh)
 
 	go pc.Run(1, stopCh)
 	go fc.Run(1, stopCh)
 
+	if cfg.TLSCertFile != "" {
+		as := admission.New(&admission.Config{
+			Addr:                    cfg.AdmissionAddr,
+			CertFile:                cfg.TLSCertFile,
+			KeyFile:                 cfg.TLSKeyFile,
+			Defaulter:               cfg.Defaulter,
+			GracefulShutdownTimeout: cfg.GracefulShutdownTimeout,
+		})
+		go func() {
+			if err := as.Run(stopCh); err != nil {
+				klog.Errorf("run admission server failed: %v", err)
+			}
+		}()
+	}
+
 	<-stopCh
 
 	return nil
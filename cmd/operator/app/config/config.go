@@ -2,14 +2,18 @@
 package config
 
 import (
+	"time"
+
 	"k8s.io/client-go/informers"
 	batchinformers "k8s.io/client-go/informers/batch/v1"
 	coreinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
 
+	"github.com/liubog2008/oooops/pkg/apis/mario/defaults"
 	"github.com/liubog2008/oooops/pkg/client/clientset"
 	extinformers "github.com/liubog2008/oooops/pkg/client/informers"
 	marioinformers "github.com/liubog2008/oooops/pkg/client/informers/mario/v1alpha1"
+	"github.com/liubog2008/oooops/pkg/controller/flow"
 )
 
 // Config defines config of operator
@@ -23,6 +27,9 @@ type Config struct {
 
 	PodInformerFactory informers.SharedInformerFactory
 
+	// SecretInformerFactory defines informer factory of mario token secrets
+	SecretInformerFactory informers.SharedInformerFactory
+
 	// ExtInformerFactory defines extension informer factory
 	ExtInformerFactory extinformers.SharedInformerFactory
 
@@ -32,11 +39,34 @@ type Config struct {
 
 	FlowInformer marioinformers.FlowInformer
 
+	ActionInformer marioinformers.ActionInformer
+
 	JobInformer batchinformers.JobInformer
 
 	PVCInformer coreinformers.PersistentVolumeClaimInformer
 
 	ConfigMapInformer coreinformers.ConfigMapInformer
 
+	// SecretInformer only watches mario token secrets
+	SecretInformer coreinformers.SecretInformer
+
 	PodInformer coreinformers.PodInformer
+
+	// EventTTL defines how long consumed or ignored events are retained
+	EventTTL time.Duration
+
+	// Defaulter sets defaults of pipes and flows
+	Defaulter defaults.Interface
+
+	// PodPolicies defines pod policies of namespaces where flows run
+	PodPolicies *flow.PodPolicies
+
+	// AdmissionAddr defines listen address of admission webhooks
+	AdmissionAddr string
+	// TLSCertFile and TLSKeyFile define tls cert and key of admission webhooks,
+	// admission webhooks are disabled if they are empty
+	TLSCertFile string
+	TLSKeyFile  string
+
+	GracefulShutdownTimeout time.Duration
 }
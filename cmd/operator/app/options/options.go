@@ -3,8 +3,11 @@ package options
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/informers"
@@ -12,9 +15,11 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 
 	"github.com/liubog2008/oooops/cmd/operator/app/config"
+	"github.com/liubog2008/oooops/pkg/apis/mario/defaults"
 	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
 	"github.com/liubog2008/oooops/pkg/client/clientset"
 	extinformers "github.com/liubog2008/oooops/pkg/client/informers"
+	"github.com/liubog2008/oooops/pkg/controller/flow"
 )
 
 // Options defines running options of operator
@@ -22,6 +27,24 @@ type Options struct {
 	Kubeconfig string
 
 	Namespace string
+
+	EventTTL time.Duration
+
+	// WorkspaceVolumeSize and WorkspaceStorageClass define default volume
+	// to store git code of flows
+	WorkspaceVolumeSize   string
+	WorkspaceStorageClass string
+
+	// PodPolicyFile defines file which stores pod policies of namespaces
+	PodPolicyFile string
+
+	// AdmissionAddr defines listen address of admission webhooks,
+	// admission webhooks are served only if tls cert and key are set
+	AdmissionAddr string
+	TLSCertFile   string
+	TLSKeyFile    string
+
+	GracefulShutdownTimeout time.Duration
 }
 
 // NewOptions returns new running options
@@ -29,6 +52,12 @@ func NewOptions() (*Options, error) {
 	opt := &Options{
 		Kubeconfig: "",
 		Namespace:  "default",
+		EventTTL:   24 * time.Hour,
+
+		WorkspaceVolumeSize: "1Gi",
+
+		AdmissionAddr:           ":8443",
+		GracefulShutdownTimeout: 20 * time.Second,
 	}
 
 	return opt, nil
@@ -40,10 +69,71 @@ func (opt *Options) AddFlags(fs *pflag.FlagSet) {
 		"kubeconfig for cluster")
 	fs.StringVar(&opt.Namespace, "namespace", opt.Namespace,
 		"namespace which operator watches, if empty, all namespaces will be watched")
+	fs.DurationVar(&opt.EventTTL, "event-ttl", opt.EventTTL,
+		"how long consumed or ignored events are retained, 0 means they will never be deleted")
+
+	fs.StringVar(&opt.WorkspaceVolumeSize, "workspace-volume-size", opt.WorkspaceVolumeSize,
+		"default size of volume to store git code of flows")
+	fs.StringVar(&opt.WorkspaceStorageClass, "workspace-storage-class", opt.WorkspaceStorageClass,
+		"default storage class of volume to store git code of flows, if empty, default storage class of cluster will be used")
+
+	fs.StringVar(&opt.PodPolicyFile, "pod-policy-file", opt.PodPolicyFile,
+		"file which stores pod policies of action pods, if empty, only default service account and unprivileged container are allowed")
+
+	fs.StringVar(&opt.AdmissionAddr, "admission-addr", opt.AdmissionAddr,
+		"listen address of admission webhooks")
+	fs.StringVar(&opt.TLSCertFile, "tls-cert-file", opt.TLSCertFile,
+		"file which stores tls cert of admission webhooks, admission webhooks are disabled if it is empty")
+	fs.StringVar(&opt.TLSKeyFile, "tls-private-key-file", opt.TLSKeyFile,
+		"file which stores tls private key of admission webhooks")
+	fs.DurationVar(
+		&opt.GracefulShutdownTimeout,
+		"graceful-shutdown-timeout",
+		opt.GracefulShutdownTimeout,
+		"graceful shutdown timeout of admission webhooks",
+	)
+}
+
+// volumeClaimTemplate returns default template of volume to store git code
+func (opt *Options) volumeClaimTemplate(size resource.Quantity) *corev1.PersistentVolumeClaim {
+	pvc := &corev1.PersistentVolumeClaim{
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{
+				corev1.ReadWriteOnce,
+			},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: size,
+				},
+			},
+		},
+	}
+	if opt.WorkspaceStorageClass != "" {
+		class := opt.WorkspaceStorageClass
+		pvc.Spec.StorageClassName = &class
+	}
+	return pvc
 }
 
 // Config parse options to config
 func (opt *Options) Config() (*config.Config, error) {
+	if opt.TLSCertFile != "" && opt.TLSKeyFile == "" {
+		return nil, fmt.Errorf("tls private key file must be set with tls cert file")
+	}
+
+	size, err := resource.ParseQuantity(opt.WorkspaceVolumeSize)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse workspace volume size (%v): %v", opt.WorkspaceVolumeSize, err)
+	}
+
+	var podPolicies *flow.PodPolicies
+	if opt.PodPolicyFile != "" {
+		podPolicies, err = flow.LoadPodPolicies(opt.PodPolicyFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	restConfig, err := clientcmd.BuildConfigFromFlags("", opt.Kubeconfig)
 	if err != nil {
 		return nil, fmt.Errorf("can't parse kubeconfig from (%v)", opt.Kubeconfig)
@@ -60,14 +150,16 @@ func (opt *Options) Config() (*config.Config, error) {
 	}
 
 	var (
-		kubeInformerOpts []informers.SharedInformerOption
-		podInformerOpts  []informers.SharedInformerOption
-		extInformerOpts  []extinformers.SharedInformerOption
+		kubeInformerOpts   []informers.SharedInformerOption
+		podInformerOpts    []informers.SharedInformerOption
+		secretInformerOpts []informers.SharedInformerOption
+		extInformerOpts    []extinformers.SharedInformerOption
 	)
 
 	if len(opt.Namespace) != 0 {
 		kubeInformerOpts = append(kubeInformerOpts, informers.WithNamespace(opt.Namespace))
 		podInformerOpts = append(podInformerOpts, informers.WithNamespace(opt.Namespace))
+		secretInformerOpts = append(secretInformerOpts, informers.WithNamespace(opt.Namespace))
 		extInformerOpts = append(extInformerOpts, extinformers.WithNamespace(opt.Namespace))
 	}
 
@@ -83,35 +175,61 @@ func (opt *Options) Config() (*config.Config, error) {
 		},
 	))
 
+	// only watch mario token secrets, other secrets are got when they are used
+	secretInformerOpts = append(secretInformerOpts, informers.WithTweakListOptions(
+		func(opts *metav1.ListOptions) {
+			opts.LabelSelector = v1alpha1.DefaultFlowTokenLabelKey
+		},
+	))
+
 	kubeInformerFactory := informers.NewSharedInformerFactoryWithOptions(kubeClient, 0, kubeInformerOpts...)
 	podInformerFactory := informers.NewSharedInformerFactoryWithOptions(kubeClient, 0, podInformerOpts...)
+	secretInformerFactory := informers.NewSharedInformerFactoryWithOptions(kubeClient, 0, secretInformerOpts...)
 	extInformerFactory := extinformers.NewSharedInformerFactoryWithOptions(extClient, 0, extInformerOpts...)
 
 	eventInformer := extInformerFactory.Mario().V1alpha1().Events()
 	pipeInformer := extInformerFactory.Mario().V1alpha1().Pipes()
 	flowInformer := extInformerFactory.Mario().V1alpha1().Flows()
+	actionInformer := extInformerFactory.Mario().V1alpha1().Actions()
 
 	jobInformer := kubeInformerFactory.Batch().V1().Jobs()
 	pvcInformer := kubeInformerFactory.Core().V1().PersistentVolumeClaims()
 	cmInformer := kubeInformerFactory.Core().V1().ConfigMaps()
+	secretInformer := secretInformerFactory.Core().V1().Secrets()
 	podInformer := podInformerFactory.Core().V1().Pods()
 
 	c := &config.Config{
 		KubeClient: kubeClient,
 		ExtClient:  extClient,
 
-		KubeInformerFactory: kubeInformerFactory,
-		PodInformerFactory:  podInformerFactory,
-		ExtInformerFactory:  extInformerFactory,
+		KubeInformerFactory:   kubeInformerFactory,
+		PodInformerFactory:    podInformerFactory,
+		SecretInformerFactory: secretInformerFactory,
+		ExtInformerFactory:    extInformerFactory,
 
-		EventInformer: eventInformer,
-		PipeInformer:  pipeInformer,
-		FlowInformer:  flowInformer,
+		EventInformer:  eventInformer,
+		PipeInformer:   pipeInformer,
+		FlowInformer:   flowInformer,
+		ActionInformer: actionInformer,
 
 		JobInformer:       jobInformer,
 		PVCInformer:       pvcInformer,
 		ConfigMapInformer: cmInformer,
+		SecretInformer:    secretInformer,
 		PodInformer:       podInformer,
+
+		EventTTL: opt.EventTTL,
+
+		Defaulter: defaults.New(&defaults.Config{
+			VolumeClaimTemplate: opt.volumeClaimTemplate(size),
+		}),
+
+		PodPolicies: podPolicies,
+
+		AdmissionAddr:           opt.AdmissionAddr,
+		TLSCertFile:             opt.TLSCertFile,
+		TLSKeyFile:              opt.TLSKeyFile,
+		GracefulShutdownTimeout: opt.GracefulShutdownTimeout,
 	}
 
 	return c, nil
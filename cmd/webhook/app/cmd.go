@@ -0,0 +1,91 @@
+// Package app defines webhook app command
+package app
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/klog"
+
+	"github.com/liubog2008/oooops/cmd/webhook/app/config"
+	"github.com/liubog2008/oooops/cmd/webhook/app/options"
+	"github.com/liubog2008/oooops/pkg/version"
+	"github.com/liubog2008/oooops/pkg/webhook"
+)
+
+// NewCommand returns app command
+func NewCommand() *cobra.Command {
+	opts, err := options.NewOptions()
+	if err != nil {
+		klog.Fatalf("can't get options: %v", err)
+	}
+	cmd := &cobra.Command{
+		Use:  "webhook",
+		Long: "webhook receives webhooks of git hosting services and creates events",
+		Run: func(cmd *cobra.Command, args []string) {
+			klog.Infof("Version: %v", version.Version())
+			printFlags(cmd.Flags())
+
+			cfg, err := opts.Config()
+			if err != nil {
+				klog.Fatalf("can't parse options to config: %v", err)
+			}
+
+			sig := make(chan os.Signal, 1)
+			signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+
+			stopCh := make(chan struct{})
+
+			go func() {
+				<-sig
+				close(stopCh)
+			}()
+
+			if err := Run(cfg, stopCh); err != nil {
+				klog.Fatalf("run webhook failed: %v", err)
+			}
+		},
+	}
+	opts.AddFlags(cmd.Flags())
+
+	cmd.AddCommand(NewVersionCmd())
+
+	return cmd
+}
+
+// NewVersionCmd return cmd reports version
+func NewVersionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:  "version",
+		Long: "webhook version",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Printf("Version: %v\n", version.Version())
+		},
+	}
+	return cmd
+}
+
+// Run runs the webhook server
+func Run(cfg *config.Config, stopCh chan struct{}) error {
+	s := webhook.New(&webhook.Config{
+		ExtClient: cfg.ExtClient,
+
+		Namespace: cfg.Namespace,
+		Secret:    cfg.Secret,
+
+		Addr:                    cfg.Addr,
+		GracefulShutdownTimeout: cfg.GracefulShutdownTimeout,
+	})
+
+	return s.Run(stopCh)
+}
+
+func printFlags(fs *pflag.FlagSet) {
+	fs.VisitAll(func(f *pflag.Flag) {
+		klog.Infof("FLAG: --%v=%v", f.Name, f.Value)
+	})
+}
@@ -0,0 +1,18 @@
+package config
+
+import (
+	"time"
+
+	"github.com/liubog2008/oooops/pkg/client/clientset"
+)
+
+// Config defines config of webhook
+type Config struct {
+	ExtClient clientset.Interface
+
+	Namespace string
+	Secret    []byte
+
+	Addr                    string
+	GracefulShutdownTimeout time.Duration
+}
@@ -0,0 +1,97 @@
+// Package options defines options of webhook
+package options
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/liubog2008/oooops/cmd/webhook/app/config"
+	"github.com/liubog2008/oooops/pkg/client/clientset"
+)
+
+// Options defines running options of webhook
+type Options struct {
+	Kubeconfig string
+
+	Namespace string
+
+	// SecretFile defines file which stores secret to verify webhooks,
+	// secret is read from file to avoid being seen in args
+	SecretFile string
+
+	Addr                    string
+	GracefulShutdownTimeout time.Duration
+}
+
+// NewOptions returns new running options
+func NewOptions() (*Options, error) {
+	opt := &Options{
+		Kubeconfig:              "",
+		Namespace:               "default",
+		Addr:                    ":8080",
+		GracefulShutdownTimeout: 20 * time.Second,
+	}
+
+	return opt, nil
+}
+
+// AddFlags adds flags for webhook options
+func (opt *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&opt.Kubeconfig, "kubeconfig", opt.Kubeconfig,
+		"kubeconfig for cluster")
+	fs.StringVar(&opt.Namespace, "namespace", opt.Namespace,
+		"namespace which events are created in")
+	fs.StringVar(&opt.SecretFile, "secret-file", opt.SecretFile,
+		"file which stores secret shared with git hosting services to verify webhooks")
+
+	fs.StringVar(&opt.Addr, "addr", opt.Addr, "listen address")
+	fs.DurationVar(
+		&opt.GracefulShutdownTimeout,
+		"graceful-shutdown-timeout",
+		opt.GracefulShutdownTimeout,
+		"graceful shutdown timeout",
+	)
+}
+
+// Config parse options to config
+func (opt *Options) Config() (*config.Config, error) {
+	if opt.SecretFile == "" {
+		return nil, fmt.Errorf("secret file must be set to verify webhooks")
+	}
+
+	secret, err := ioutil.ReadFile(opt.SecretFile)
+	if err != nil {
+		return nil, fmt.Errorf("can't read secret from (%v): %v", opt.SecretFile, err)
+	}
+	secret = bytes.TrimSpace(secret)
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("secret in (%v) is empty", opt.SecretFile)
+	}
+
+	restConfig, err := clientcmd.BuildConfigFromFlags("", opt.Kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse kubeconfig from (%v)", opt.Kubeconfig)
+	}
+
+	extClient, err := clientset.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("can't new extension client: %v", err)
+	}
+
+	c := &config.Config{
+		ExtClient: extClient,
+
+		Namespace: opt.Namespace,
+		Secret:    secret,
+
+		Addr:                    opt.Addr,
+		GracefulShutdownTimeout: opt.GracefulShutdownTimeout,
+	}
+
+	return c, nil
+}
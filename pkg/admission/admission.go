@@ -0,0 +1,271 @@
+// Package admission defines a server which serves admission webhooks
+// of mario objects
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"time"
+
+	"k8s.io/api/admission/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/klog"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/defaults"
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	"github.com/liubog2008/oooops/pkg/apis/mario/validation"
+	"github.com/liubog2008/oooops/pkg/client/clientset/scheme"
+	"github.com/liubog2008/oooops/pkg/utils/graceful"
+)
+
+const (
+	// maxReviewSize defines max size of admission review
+	maxReviewSize = 3 << 20
+)
+
+// Interface defines interface to run admission server
+type Interface interface {
+	Run(stopCh <-chan struct{}) error
+}
+
+// Config defines config to run admission server
+type Config struct {
+	Addr string
+	// CertFile and KeyFile define tls cert and key of server,
+	// admission webhooks must be served by https
+	CertFile string
+	KeyFile  string
+
+	// Defaulter sets defaults of pipes and flows in mutating webhook
+	Defaulter defaults.Interface
+
+	GracefulShutdownTimeout time.Duration
+}
+
+type server struct {
+	addr     string
+	certFile string
+	keyFile  string
+
+	defaulter defaults.Interface
+
+	gracefulShutdownTimeout time.Duration
+}
+
+// New returns an admission server
+func New(c *Config) Interface {
+	return &server{
+		addr:                    c.Addr,
+		certFile:                c.CertFile,
+		keyFile:                 c.KeyFile,
+		defaulter:               c.Defaulter,
+		gracefulShutdownTimeout: c.GracefulShutdownTimeout,
+	}
+}
+
+func (s *server) Run(stopCh <-chan struct{}) error {
+	srv := &http.Server{
+		Addr:         s.addr,
+		Handler:      s.handler(),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		IdleTimeout:  15 * time.Second,
+	}
+
+	g := graceful.New()
+
+	g.OnShutdown(func(ctx context.Context) {
+		if err := srv.Shutdown(ctx); err != nil {
+			klog.Errorf("Could not gracefully shutdown the admission server: %v", err)
+		}
+	})
+
+	go func() {
+		if err := srv.ListenAndServeTLS(s.certFile, s.keyFile); err != nil {
+			klog.Infof("admission server finished: %v", err)
+		}
+	}()
+
+	g.WaitForShutdown(stopCh, s.gracefulShutdownTimeout)
+
+	return nil
+}
+
+func (s *server) handler() http.Handler {
+	router := http.NewServeMux()
+	router.HandleFunc("/healthz", s.health)
+	router.HandleFunc("/mutate", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, s.mutate)
+	})
+	router.HandleFunc("/validate", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, validate)
+	})
+	return router
+}
+
+func (s *server) health(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte("ok")); err != nil {
+		klog.Errorf("can't write response: %v", err)
+	}
+}
+
+// admitFunc handles admission request and returns the response
+type admitFunc func(req *v1beta1.AdmissionRequest) *v1beta1.AdmissionResponse
+
+// serve decodes admission review from request and writes review with response of admit
+func serve(w http.ResponseWriter, r *http.Request, admit admitFunc) {
+	if r.Method != http.MethodPost {
+		http.Error(w, fmt.Sprintf("method %s is not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxReviewSize))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("can't read body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	review := v1beta1.AdmissionReview{}
+	if err := json.Unmarshal(body, &review); err != nil {
+		http.Error(w, fmt.Sprintf("can't decode admission review: %v", err), http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		http.Error(w, "request of admission review is missing", http.StatusBadRequest)
+		return
+	}
+
+	resp := admit(review.Request)
+	resp.UID = review.Request.UID
+	review.Response = resp
+	review.Request = nil
+
+	b, err := json.Marshal(&review)
+	if err != nil {
+		klog.Errorf("can't marshal admission review: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(b); err != nil {
+		klog.Errorf("can't write whole response: %v", err)
+	}
+}
+
+// validate rejects pipes, flows and marios which can't pass validation
+func validate(req *v1beta1.AdmissionRequest) *v1beta1.AdmissionResponse {
+	if req.Operation == v1beta1.Delete || req.Kind.Group != v1alpha1.SchemeGroupVersion.Group {
+		return allowed()
+	}
+
+	obj, err := decode(req)
+	if err != nil {
+		return denied(apierrors.NewBadRequest(err.Error()))
+	}
+
+	var errs field.ErrorList
+	switch o := obj.(type) {
+	case *v1alpha1.Pipe:
+		errs = validation.ValidatePipe(o)
+	case *v1alpha1.Flow:
+		errs = validation.ValidateFlow(o)
+	case *v1alpha1.Mario:
+		errs = validation.ValidateMario(o)
+	default:
+		return allowed()
+	}
+
+	if len(errs) != 0 {
+		klog.V(4).Infof("%s %s/%s is rejected: %v", req.Kind.Kind, req.Namespace, req.Name, errs.ToAggregate())
+		gk := v1alpha1.SchemeGroupVersion.WithKind(req.Kind.Kind).GroupKind()
+		return denied(apierrors.NewInvalid(gk, req.Name, errs))
+	}
+	return allowed()
+}
+
+// mutate sets defaults of pipes and flows, spec of object
+// is replaced by the defaulted one
+func (s *server) mutate(req *v1beta1.AdmissionRequest) *v1beta1.AdmissionResponse {
+	if req.Operation == v1beta1.Delete || req.Kind.Group != v1alpha1.SchemeGroupVersion.Group {
+		return allowed()
+	}
+
+	obj, err := decode(req)
+	if err != nil {
+		return denied(apierrors.NewBadRequest(err.Error()))
+	}
+
+	var original, spec interface{}
+	switch o := obj.(type) {
+	case *v1alpha1.Pipe:
+		original = o.Spec.DeepCopy()
+		s.defaulter.DefaultPipe(o)
+		spec = &o.Spec
+	case *v1alpha1.Flow:
+		original = o.Spec.DeepCopy()
+		s.defaulter.DefaultFlow(o)
+		spec = &o.Spec
+	default:
+		return allowed()
+	}
+
+	if reflect.DeepEqual(original, spec) {
+		return allowed()
+	}
+
+	patch, err := json.Marshal([]jsonPatchOperation{
+		{
+			// add replaces the member if it exists
+			Op:    "add",
+			Path:  "/spec",
+			Value: spec,
+		},
+	})
+	if err != nil {
+		return denied(apierrors.NewInternalError(err))
+	}
+
+	patchType := v1beta1.PatchTypeJSONPatch
+	return &v1beta1.AdmissionResponse{
+		Allowed:   true,
+		Patch:     patch,
+		PatchType: &patchType,
+	}
+}
+
+// jsonPatchOperation defines an operation of json patch
+type jsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// decode decodes object in admission request
+func decode(req *v1beta1.AdmissionRequest) (runtime.Object, error) {
+	obj, _, err := scheme.Codecs.UniversalDeserializer().Decode(req.Object.Raw, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("can't decode %s: %v", req.Kind.Kind, err)
+	}
+	return obj, nil
+}
+
+func allowed() *v1beta1.AdmissionResponse {
+	return &v1beta1.AdmissionResponse{
+		Allowed: true,
+	}
+}
+
+func denied(err *apierrors.StatusError) *v1beta1.AdmissionResponse {
+	status := err.Status()
+	return &v1beta1.AdmissionResponse{
+		Allowed: false,
+		Result:  &status,
+	}
+}
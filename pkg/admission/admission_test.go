@@ -0,0 +1,134 @@
+package admission
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/defaults"
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+// newReview returns a function which posts pipe in admission review to path
+func newReview(t *testing.T, h http.Handler, path string) func(obj string) *v1beta1.AdmissionReview {
+	return func(obj string) *v1beta1.AdmissionReview {
+		req := v1beta1.AdmissionReview{
+			Request: &v1beta1.AdmissionRequest{
+				UID:       "test",
+				Kind:      metav1.GroupVersionKind{Group: "mario.oooops.com", Version: "v1alpha1", Kind: "Pipe"},
+				Name:      "test-pipe",
+				Namespace: "default",
+				Operation: v1beta1.Create,
+				Object:    runtime.RawExtension{Raw: []byte(obj)},
+			},
+		}
+		b, err := json.Marshal(&req)
+		assert.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(b)))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		resp := v1beta1.AdmissionReview{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		if !assert.NotNil(t, resp.Response) {
+			t.FailNow()
+		}
+		assert.Equal(t, req.Request.UID, resp.Response.UID)
+		return &resp
+	}
+}
+
+func TestValidate(t *testing.T) {
+	s := New(&Config{}).(*server)
+	review := newReview(t, s.handler(), "/validate")
+
+	valid := `{
+		"apiVersion": "mario.oooops.com/v1alpha1",
+		"kind": "Pipe",
+		"metadata": {"name": "test-pipe", "namespace": "default"},
+		"spec": {
+			"git": {"repo": "https://github.com/liubog2008/oooops", "volumeClaimTemplate": {}},
+			"stages": [{"name": "compile", "action": "compile"}]
+		}
+	}`
+	assert.True(t, review(valid).Response.Allowed)
+
+	invalid := `{
+		"apiVersion": "mario.oooops.com/v1alpha1",
+		"kind": "Pipe",
+		"metadata": {"name": "test-pipe", "namespace": "default"},
+		"spec": {
+			"git": {"repo": "https://github.com/liubog2008/oooops"},
+			"stages": [{"name": "compile", "action": "compile"}, {"name": "compile", "action": "test"}]
+		}
+	}`
+	resp := review(invalid).Response
+	assert.False(t, resp.Allowed)
+	if !assert.NotNil(t, resp.Result) {
+		return
+	}
+	assert.Equal(t, metav1.StatusReasonInvalid, resp.Result.Reason)
+	causes := []string{}
+	for _, cause := range resp.Result.Details.Causes {
+		causes = append(causes, cause.Field)
+	}
+	assert.Equal(t, []string{"spec.git.volumeClaimTemplate", "spec.stages[1].name"}, causes)
+}
+
+func TestMutate(t *testing.T) {
+	s := New(&Config{
+		Defaulter: defaults.New(&defaults.Config{
+			VolumeClaimTemplate: &corev1.PersistentVolumeClaim{},
+		}),
+	}).(*server)
+	review := newReview(t, s.handler(), "/mutate")
+
+	defaulted := `{
+		"apiVersion": "mario.oooops.com/v1alpha1",
+		"kind": "Pipe",
+		"metadata": {"name": "test-pipe", "namespace": "default"},
+		"spec": {
+			"git": {"repo": "https://github.com/liubog2008/oooops", "volumeClaimTemplate": {"spec": {}}},
+			"when": ["git:push"],
+			"stages": [{"name": "compile", "action": "compile"}]
+		}
+	}`
+	resp := review(defaulted).Response
+	assert.True(t, resp.Allowed)
+	assert.Empty(t, resp.Patch)
+
+	minimal := `{
+		"apiVersion": "mario.oooops.com/v1alpha1",
+		"kind": "Pipe",
+		"metadata": {"name": "test-pipe", "namespace": "default"},
+		"spec": {
+			"git": {"repo": "https://github.com/liubog2008/oooops"},
+			"stages": [{"name": "compile"}]
+		}
+	}`
+	resp = review(minimal).Response
+	assert.True(t, resp.Allowed)
+
+	patch := []struct {
+		Op    string            `json:"op"`
+		Path  string            `json:"path"`
+		Value v1alpha1.PipeSpec `json:"value"`
+	}{}
+	assert.NoError(t, json.Unmarshal(resp.Patch, &patch))
+	if !assert.Len(t, patch, 1) {
+		return
+	}
+	assert.Equal(t, "/spec", patch[0].Path)
+	assert.Equal(t, []v1alpha1.When{v1alpha1.Push}, patch[0].Value.When)
+	assert.Equal(t, "compile", patch[0].Value.Stages[0].Action)
+	assert.NotNil(t, patch[0].Value.Git.VolumeClaimTemplate)
+}
@@ -0,0 +1,58 @@
+// Package defaults defines defaulting of mario objects, it is shared by
+// the admission webhook and controllers.
+// Static defaults are registered in scheme and others depend on config of operator
+package defaults
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	"github.com/liubog2008/oooops/pkg/client/clientset/scheme"
+)
+
+// Interface defines interface to set defaults of mario objects
+type Interface interface {
+	// DefaultPipe sets defaults of pipe
+	DefaultPipe(pipe *v1alpha1.Pipe)
+	// DefaultFlow sets defaults of flow
+	DefaultFlow(flow *v1alpha1.Flow)
+}
+
+// Config defines config of defaulting
+type Config struct {
+	// VolumeClaimTemplate defines default template of volume to store git code
+	VolumeClaimTemplate *corev1.PersistentVolumeClaim
+}
+
+type defaulter struct {
+	volumeClaimTemplate *corev1.PersistentVolumeClaim
+}
+
+// New returns a defaulter
+func New(c *Config) Interface {
+	return &defaulter{
+		volumeClaimTemplate: c.VolumeClaimTemplate,
+	}
+}
+
+func (d *defaulter) DefaultPipe(pipe *v1alpha1.Pipe) {
+	scheme.Scheme.Default(pipe)
+	d.defaultGit(&pipe.Spec.Git)
+}
+
+func (d *defaulter) DefaultFlow(flow *v1alpha1.Flow) {
+	scheme.Scheme.Default(flow)
+	d.defaultGit(&flow.Spec.Git)
+
+	// all jobs of flow are selected and filtered by owner
+	if flow.Spec.Selector == nil {
+		flow.Spec.Selector = &metav1.LabelSelector{}
+	}
+}
+
+func (d *defaulter) defaultGit(git *v1alpha1.Git) {
+	if git.VolumeClaimTemplate == nil && d.volumeClaimTemplate != nil {
+		git.VolumeClaimTemplate = d.volumeClaimTemplate.DeepCopy()
+	}
+}
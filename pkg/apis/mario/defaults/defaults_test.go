@@ -0,0 +1,66 @@
+package defaults
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	"github.com/liubog2008/oooops/pkg/apis/mario/validation"
+	"github.com/liubog2008/oooops/pkg/client/clientset/scheme"
+)
+
+func TestDefaultPipe(t *testing.T) {
+	template := &corev1.PersistentVolumeClaim{
+		Spec: corev1.PersistentVolumeClaimSpec{
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: resource.MustParse("1Gi"),
+				},
+			},
+		},
+	}
+	d := New(&Config{
+		VolumeClaimTemplate: template,
+	})
+
+	b, err := ioutil.ReadFile("../../../../test/testdata/test-pipe.yaml")
+	assert.NoError(t, err)
+	data := strings.Replace(string(b), "${NAMESPACE}", "default", -1)
+
+	obj, _, err := scheme.Codecs.UniversalDeserializer().Decode([]byte(data), nil, nil)
+	assert.NoError(t, err)
+	pipe, ok := obj.(*v1alpha1.Pipe)
+	if !assert.True(t, ok) {
+		return
+	}
+
+	pipe.Spec.When = nil
+	pipe.Spec.Stages[0].Action = ""
+	assert.NotEmpty(t, validation.ValidatePipe(pipe))
+
+	d.DefaultPipe(pipe)
+	assert.Empty(t, validation.ValidatePipe(pipe))
+	assert.Equal(t, []v1alpha1.When{v1alpha1.Push}, pipe.Spec.When)
+	assert.Equal(t, pipe.Spec.Stages[0].Name, pipe.Spec.Stages[0].Action)
+	assert.Equal(t, template, pipe.Spec.Git.VolumeClaimTemplate)
+	assert.False(t, template == pipe.Spec.Git.VolumeClaimTemplate)
+}
+
+func TestDefaultFlow(t *testing.T) {
+	d := New(&Config{})
+
+	flow := &v1alpha1.Flow{
+		Spec: v1alpha1.FlowSpec{
+			Stages: []v1alpha1.Stage{{Name: "build"}},
+		},
+	}
+	d.DefaultFlow(flow)
+	assert.NotNil(t, flow.Spec.Selector)
+	assert.Nil(t, flow.Spec.Git.VolumeClaimTemplate)
+	assert.Equal(t, "build", flow.Spec.Stages[0].Action)
+}
@@ -0,0 +1,30 @@
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func init() {
+	localSchemeBuilder.Register(addDefaultingFuncs)
+}
+
+func addDefaultingFuncs(scheme *runtime.Scheme) error {
+	return RegisterDefaults(scheme)
+}
+
+// SetDefaults_Pipe sets defaults of pipe, pipe is triggered by push by default
+// nolint: golint
+func SetDefaults_Pipe(obj *Pipe) {
+	if len(obj.Spec.When) == 0 {
+		obj.Spec.When = []When{Push}
+	}
+}
+
+// SetDefaults_Stage sets defaults of stage, action of stage is the same
+// as its name by default
+// nolint: golint
+func SetDefaults_Stage(obj *Stage) {
+	if obj.Action == "" {
+		obj.Action = obj.Name
+	}
+}
@@ -1,5 +1,6 @@
 // +k8s:deepcopy-gen=package,register
 // +k8s:openapi-gen=true
+// +k8s:defaulter-gen=TypeMeta
 
 // Package v1alpha1 is the v1alpha1 version of the mario API.
 // +groupName=mario.oooops.com
@@ -75,6 +75,9 @@ type Mario struct {
 // MarioSpec defines spec of Mario
 type MarioSpec struct {
 	// Imports defines import path of external mario action
+	// Path is the name of Action in the same namespace of flow,
+	// actions of other namespaces can't be imported
+	// +optional
 	Imports []string `json:"imports,omitempty" protobuf:"bytes,1,rep,name=imports"`
 	// Actions defines actions of the project
 	// e.g. compile, test
@@ -102,6 +105,45 @@ type When string
 const (
 	// Push means event when git push
 	Push When = "git:push"
+	// Tag means event when git tag is pushed
+	Tag When = "git:tag"
+	// PullRequest means event when pull request is opened, synchronized or reopened
+	PullRequest When = "git:pull_request"
+	// Schedule means event generated by pipe at scheduled times
+	Schedule When = "schedule"
+)
+
+const (
+	// EventExtraProvider defines key of git hosting provider in event extra
+	EventExtraProvider = "provider"
+	// EventExtraCommit defines key of commit sha in event extra
+	EventExtraCommit = "commit"
+	// EventExtraBefore defines key of commit sha before pushing in event extra
+	EventExtraBefore = "before"
+	// EventExtraPusher defines key of user who pushes in event extra
+	EventExtraPusher = "pusher"
+	// EventExtraCompareURL defines key of url to compare changes in event extra
+	EventExtraCompareURL = "compareURL"
+	// EventExtraCloneURL defines key of url to clone repo in event extra
+	EventExtraCloneURL = "cloneURL"
+	// EventExtraPullRequest defines key of pull request number in event extra
+	EventExtraPullRequest = "pullRequest"
+	// EventExtraAction defines key of pull request action in event extra,
+	// e.g. opened, synchronized and reopened
+	EventExtraAction = "action"
+	// EventExtraBaseRef defines key of base ref of pull request in event extra,
+	// e.g. refs/heads/master
+	EventExtraBaseRef = "baseRef"
+	// EventExtraHeadRef defines key of head ref of pull request in event extra,
+	// e.g. refs/heads/feature
+	EventExtraHeadRef = "headRef"
+	// EventExtraHeadRepo defines key of url of repo which head branch of pull request
+	// belongs to in event extra, it is different from repo of event if pull request is from a fork
+	EventExtraHeadRepo = "headRepo"
+	// EventExtraPipe defines key of pipe which generates the scheduled event
+	EventExtraPipe = "pipe"
+	// EventExtraScheduledTime defines key of scheduled time of event in RFC3339
+	EventExtraScheduledTime = "scheduledTime"
 )
 
 // +genclient
@@ -112,6 +154,11 @@ const (
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Repo",type=string,JSONPath=`.spec.git.repo`
 // +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Last Flow",type=string,JSONPath=`.status.lastFlow.name`
+// +kubebuilder:printcolumn:name="Last Phase",type=string,JSONPath=`.status.lastFlow.phase`
+// +kubebuilder:printcolumn:name="Last Successful Ref",type=string,JSONPath=`.status.lastSuccessfulRef`
+// +kubebuilder:printcolumn:name="Active",type=integer,JSONPath=`.status.activeFlows`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 type Pipe struct {
 	metav1.TypeMeta `json:",inline"`
 	// +optional
@@ -133,7 +180,7 @@ type PipeSpec struct {
 	// +optional
 	// +nullable
 	Selector *metav1.LabelSelector `json:"selector" protobuf:"bytes,1,opt,name=selector"`
-	// When defines when pipe will be triggered
+	// When defines when pipe will be triggered, it defaults to git push
 	// +optional
 	When []When `json:"when,omitempty" protobuf:"bytes,2,rep,name=when"`
 
@@ -142,13 +189,226 @@ type PipeSpec struct {
 	// Stages defines pipe stages which will be run
 	// +optional
 	Stages []Stage `json:"stages,omitempty" protobuf:"bytes,4,rep,name=stages"`
+
+	// Timeout defines timeout of flows generated by the pipe
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty" protobuf:"bytes,5,opt,name=timeout"`
+
+	// AllowedSecrets defines names of secrets which can be mounted by
+	// actions of flows generated by the pipe
+	// +optional
+	AllowedSecrets []string `json:"allowedSecrets,omitempty" protobuf:"bytes,6,rep,name=allowedSecrets"`
+
+	// PullRequest defines options of pull request events
+	// +optional
+	PullRequest *PullRequestOptions `json:"pullRequest,omitempty" protobuf:"bytes,7,opt,name=pullRequest"`
+
+	// Schedule defines when pipe generates scheduled events,
+	// it works only if schedule is in When
+	// +optional
+	Schedule *PipeSchedule `json:"schedule,omitempty" protobuf:"bytes,8,opt,name=schedule"`
+
+	// Branches filters branches of push events by glob patterns, e.g. release/*
+	// +optional
+	Branches *PatternFilter `json:"branches,omitempty" protobuf:"bytes,9,opt,name=branches"`
+
+	// Tags filters tags of tag events by glob patterns, e.g. v*
+	// +optional
+	Tags *PatternFilter `json:"tags,omitempty" protobuf:"bytes,10,opt,name=tags"`
+
+	// Paths filters events by changed files, e.g. services/foo.
+	// A pattern matches a file or any of its parent directories,
+	// pattern without slash also matches base name of file, e.g. *.md.
+	// Events without changed files are not filtered
+	// +optional
+	Paths *PatternFilter `json:"paths,omitempty" protobuf:"bytes,11,opt,name=paths"`
+
+	// ConcurrencyPolicy defines how to deal with concurrent flows
+	// in the same concurrency group, default is Allow
+	// +optional
+	ConcurrencyPolicy ConcurrencyPolicy `json:"concurrencyPolicy,omitempty" protobuf:"bytes,12,opt,name=concurrencyPolicy"`
+
+	// ConcurrencyGroup defines key of concurrency group, flows of pipes
+	// in the same namespace with the same key are in the same group.
+	// Default is name of pipe and ref of event, i.e. flows of a branch
+	// +optional
+	ConcurrencyGroup string `json:"concurrencyGroup,omitempty" protobuf:"bytes,13,opt,name=concurrencyGroup"`
+
+	// SuccessfulFlowsHistoryLimit defines number of succeeded flows to retain,
+	// older ones will be deleted with their jobs, config maps and volumes.
+	// All succeeded flows are retained if it is not set
+	// +optional
+	SuccessfulFlowsHistoryLimit *int32 `json:"successfulFlowsHistoryLimit,omitempty" protobuf:"varint,14,opt,name=successfulFlowsHistoryLimit"`
+
+	// FailedFlowsHistoryLimit defines number of failed and cancelled flows to retain.
+	// All failed flows are retained if it is not set
+	// +optional
+	FailedFlowsHistoryLimit *int32 `json:"failedFlowsHistoryLimit,omitempty" protobuf:"varint,15,opt,name=failedFlowsHistoryLimit"`
+
+	// FlowTTLSecondsAfterFinished defines how long finished flows are retained,
+	// flows will be deleted after the ttl even if they are within history limits
+	// +optional
+	FlowTTLSecondsAfterFinished *int32 `json:"flowTTLSecondsAfterFinished,omitempty" protobuf:"varint,16,opt,name=flowTTLSecondsAfterFinished"`
+
+	// Dedupe means events with the same repo and ref share one flow,
+	// the existing flow will be updated instead of creating a new one.
+	// By default every event and commit gets its own flow
+	// +optional
+	Dedupe bool `json:"dedupe,omitempty" protobuf:"varint,17,opt,name=dedupe"`
+}
+
+// ConcurrencyPolicy defines how to deal with concurrent flows
+// +kubebuilder:validation:Enum=Allow;Forbid;Replace
+type ConcurrencyPolicy string
+
+const (
+	// AllowConcurrent allows flows to run concurrently
+	AllowConcurrent ConcurrencyPolicy = "Allow"
+	// ForbidConcurrent queues new flow until earlier flows are finished
+	ForbidConcurrent ConcurrencyPolicy = "Forbid"
+	// ReplaceConcurrent cancels earlier unfinished flows and runs the new one
+	ReplaceConcurrent ConcurrencyPolicy = "Replace"
+)
+
+// PatternFilter defines glob patterns to include and exclude names.
+// A name is matched if it matches one of include patterns and none of exclude patterns,
+// all names are included if include patterns are empty
+type PatternFilter struct {
+	// Include defines patterns of included names
+	// +optional
+	Include []string `json:"include,omitempty" protobuf:"bytes,1,rep,name=include"`
+	// Exclude defines patterns of excluded names
+	// +optional
+	Exclude []string `json:"exclude,omitempty" protobuf:"bytes,2,rep,name=exclude"`
+}
+
+// PipeSchedule defines cron schedule of pipe
+type PipeSchedule struct {
+	// Cron defines schedule in cron format, e.g. "0 2 * * *"
+	Cron string `json:"cron" protobuf:"bytes,1,opt,name=cron"`
+	// TimeZone defines time zone of cron, e.g. "Asia/Shanghai", default is UTC
+	// +optional
+	TimeZone string `json:"timeZone,omitempty" protobuf:"bytes,2,opt,name=timeZone"`
+	// Ref defines ref which will be checked out, e.g. refs/heads/master
+	Ref string `json:"ref" protobuf:"bytes,3,opt,name=ref"`
+	// StartingDeadlineSeconds defines deadline in seconds for starting
+	// the event if it misses scheduled time for any reason, e.g. operator is down.
+	// Missed schedules will be skipped if they exceed the deadline.
+	// Only the latest missed schedule will be started
+	// +optional
+	StartingDeadlineSeconds *int64 `json:"startingDeadlineSeconds,omitempty" protobuf:"varint,4,opt,name=startingDeadlineSeconds"`
+}
+
+// PullRequestCheckout defines which commit of pull request will be checked out
+// +kubebuilder:validation:Enum=Head;Merge
+type PullRequestCheckout string
+
+const (
+	// PullRequestCheckoutHead means head commit of pull request will be checked out
+	PullRequestCheckoutHead PullRequestCheckout = "Head"
+	// PullRequestCheckoutMerge means head of pull request will be merged into
+	// its base branch and the merge commit will be checked out
+	PullRequestCheckoutMerge PullRequestCheckout = "Merge"
+)
+
+// PullRequestOptions defines options of pull request events
+type PullRequestOptions struct {
+	// Checkout defines which commit will be checked out, default is Head
+	// +optional
+	Checkout PullRequestCheckout `json:"checkout,omitempty" protobuf:"bytes,1,opt,name=checkout"`
+	// Branches defines patterns of base branches, only pull requests
+	// to these branches will trigger the pipe.
+	// All pull requests will trigger the pipe if it is empty
+	// +optional
+	Branches []string `json:"branches,omitempty" protobuf:"bytes,2,rep,name=branches"`
+	// AllowForks defines whether pull requests from forks trigger the pipe.
+	// Flows of them can run untrusted code with secrets and service account of pipe,
+	// so they are ignored by default
+	// +optional
+	AllowForks bool `json:"allowForks,omitempty" protobuf:"varint,3,opt,name=allowForks"`
 }
 
 // PipeStatus defines status of pipe
-// TODO(liubog2008): add conditions  of pipe
 type PipeStatus struct {
 	// Phase defines phase of pipe
 	Phase string `json:"phase,omitempty" protobuf:"bytes,1,opt,name=phase"`
+	// LastScheduleTime defines the last time when pipe is scheduled
+	// +optional
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty" protobuf:"bytes,2,opt,name=lastScheduleTime"`
+	// LastFlow defines the latest flow generated by pipe
+	// +optional
+	LastFlow *PipeFlowStatus `json:"lastFlow,omitempty" protobuf:"bytes,3,opt,name=lastFlow"`
+	// LastSuccessfulRef defines ref of the latest succeeded flow
+	// +optional
+	LastSuccessfulRef string `json:"lastSuccessfulRef,omitempty" protobuf:"bytes,4,opt,name=lastSuccessfulRef"`
+	// ActiveFlows defines number of unfinished flows of pipe
+	// +optional
+	ActiveFlows int32 `json:"activeFlows,omitempty" protobuf:"varint,5,opt,name=activeFlows"`
+	// Conditions defines conditions of pipe
+	// +optional
+	Conditions []PipeCondition `json:"conditions,omitempty" protobuf:"bytes,6,rep,name=conditions"`
+}
+
+const (
+	// PipeIdle means pipe has no running flow
+	PipeIdle = "Idle"
+	// PipeRunning means some flows of pipe are running
+	PipeRunning = "Running"
+	// PipeError means pipe is not ready or can't generate flow
+	PipeError = "Error"
+)
+
+// PipeFlowStatus defines brief status of flow generated by pipe
+type PipeFlowStatus struct {
+	// Name of flow
+	Name string `json:"name" protobuf:"bytes,1,opt,name=name"`
+	// Phase of flow
+	// +optional
+	Phase string `json:"phase,omitempty" protobuf:"bytes,2,opt,name=phase"`
+	// Ref defines git ref of flow
+	// +optional
+	Ref string `json:"ref,omitempty" protobuf:"bytes,3,opt,name=ref"`
+}
+
+// PipeConditionType defines type of pipe condition
+type PipeConditionType string
+
+const (
+	// PipeReady means spec of pipe is valid and pipe can be triggered
+	PipeReady PipeConditionType = "Ready"
+
+	// PipeTriggerError means some watched events can't generate flows
+	PipeTriggerError PipeConditionType = "TriggerError"
+)
+
+const (
+	// PipeReasonValid means spec of pipe is valid
+	PipeReasonValid = "Valid"
+	// PipeReasonInvalid means spec of pipe is invalid, e.g. schedule can't be parsed
+	PipeReasonInvalid = "Invalid"
+
+	// PipeReasonTriggered means all watched events have generated flows
+	PipeReasonTriggered = "Triggered"
+	// PipeReasonGenerateFailed means flow of some events can't be generated
+	PipeReasonGenerateFailed = "GenerateFailed"
+)
+
+// PipeCondition defines condition of pipe
+type PipeCondition struct {
+	// Type is the type of the condition.
+	Type PipeConditionType `json:"type" protobuf:"bytes,1,opt,name=type,casttype=PipeConditionType"`
+	// Status is the status of the condition.
+	// Can be True, False, Unknown.
+	Status corev1.ConditionStatus `json:"status" protobuf:"bytes,2,opt,name=status,casttype=ConditionStatus"`
+	// Last time the condition transitioned from one status to another.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty" protobuf:"bytes,3,opt,name=lastTransitionTime"`
+	// Unique, one-word, CamelCase reason for the condition's last transition.
+	// +optional
+	Reason string `json:"reason,omitempty" protobuf:"bytes,4,opt,name=reason"`
+	// Human-readable message indicating details about last transition.
+	// +optional
+	Message string `json:"message,omitempty" protobuf:"bytes,5,opt,name=message"`
 }
 
 // +genclient
@@ -156,7 +416,7 @@ type PipeStatus struct {
 
 // Event defines event which can trigger pipe to generate flow
 // +kubebuilder:subresource:status
-// +kubebuilder:printcolumn:name="Repo",type=string,JSONPath=`.spec.git.repo`
+// +kubebuilder:printcolumn:name="Repo",type=string,JSONPath=`.spec.repo`
 // +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
 type Event struct {
 	metav1.TypeMeta `json:",inline"`
@@ -166,6 +426,68 @@ type Event struct {
 	// Spec defines desired props of Event
 	// +optional
 	Spec EventSpec `json:"spec,omitempty" protobuf:"bytes,2,opt,name=spec"`
+
+	// Status defines consumption status of Event
+	// +optional
+	// +kubebuilder:default={phase:"Pending"}
+	Status EventStatus `json:"status,omitempty" protobuf:"bytes,3,opt,name=status"`
+}
+
+const (
+	// EventPending means event has not been handled by all pipes watching it
+	EventPending = "Pending"
+	// EventConsumed means some pipes have generated flows for the event
+	EventConsumed = "Consumed"
+	// EventIgnored means no pipe watches the event or all pipes skip it
+	EventIgnored = "Ignored"
+	// EventFailed means some pipes failed to generate flows for the event
+	EventFailed = "Failed"
+)
+
+const (
+	// EventReasonPathNotChanged means no path watched by pipe is changed
+	EventReasonPathNotChanged = "PathNotChanged"
+	// EventReasonFlowPruned means flow of pipe has been pruned
+	EventReasonFlowPruned = "FlowPruned"
+	// EventReasonGenerateFailed means pipe failed to generate flow
+	EventReasonGenerateFailed = "GenerateFailed"
+	// EventReasonForkNotAllowed means pull request is from a fork which is not allowed by pipe
+	EventReasonForkNotAllowed = "ForkNotAllowed"
+)
+
+// EventStatus defines status of event
+type EventStatus struct {
+	// Phase defines phase of event
+	// +optional
+	Phase string `json:"phase,omitempty" protobuf:"bytes,1,opt,name=phase"`
+	// Pipes defines results of pipes which watch the event
+	// +optional
+	Pipes []EventPipeStatus `json:"pipes,omitempty" protobuf:"bytes,2,rep,name=pipes"`
+	// CompletionTime defines time when event is consumed or ignored,
+	// event will be deleted after ttl from completion time
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty" protobuf:"bytes,3,opt,name=completionTime"`
+}
+
+// EventPipeStatus defines result of a pipe which watches the event
+type EventPipeStatus struct {
+	// Name defines name of pipe
+	Name string `json:"name" protobuf:"bytes,1,opt,name=name"`
+	// ObservedGeneration defines generation of pipe which handles the event,
+	// event will be handled again if pipe is changed
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty" protobuf:"varint,2,opt,name=observedGeneration"`
+	// Phase defines result of pipe, it is one of Consumed, Ignored and Failed
+	Phase string `json:"phase" protobuf:"bytes,3,opt,name=phase"`
+	// Flow defines name of flow generated by pipe
+	// +optional
+	Flow string `json:"flow,omitempty" protobuf:"bytes,4,opt,name=flow"`
+	// Reason defines why event is ignored by pipe or failed
+	// +optional
+	Reason string `json:"reason,omitempty" protobuf:"bytes,5,opt,name=reason"`
+	// Message defines human readable details of reason
+	// +optional
+	Message string `json:"message,omitempty" protobuf:"bytes,6,opt,name=message"`
 }
 
 // EventSpec defines event which will trigger some pipes
@@ -182,6 +504,11 @@ type EventSpec struct {
 	// It can be used by action env
 	// +optional
 	Extra map[string]string `json:"extra" protobuf:"bytes,4,opt,name=extra"`
+
+	// ChangedFiles defines paths of files changed by the event.
+	// Empty means changed files are unknown
+	// +optional
+	ChangedFiles []string `json:"changedFiles,omitempty" protobuf:"bytes,5,rep,name=changedFiles"`
 }
 
 // Git defines git info
@@ -191,21 +518,82 @@ type Git struct {
 	// Ref defines git repo ref
 	// +optional
 	Ref string `json:"ref" protobuf:"bytes,2,opt,name=ref"`
-	// GitPullSecret defines secret for git to pull code
+	// GitPullSecret defines secret for git to pull code.
+	// For ssh, key ssh-privatekey and known_hosts are required.
+	// For https, key password is the password or token and key username is optional.
 	// +optional
 	GitPullSecret corev1.LocalObjectReference `json:"gitPullSecret" protobuf:"bytes,3,opt,name=gitPullSecret"`
-	// VolumeClaimTemplate defines template of volume to store git code
+	// VolumeClaimTemplate defines template of volume to store git code,
+	// it defaults to the workspace volume configured by operator
 	// nolint: lll
 	// +optional
 	VolumeClaimTemplate *corev1.PersistentVolumeClaim `json:"volumeClaimTemplate,omitempty" protobuf:"bytes,4,opt,name=volumeClaimTemplate"`
+	// BaseRef defines ref which Ref will be merged into, the merge commit
+	// will be checked out. It is used to test merge result of pull request
+	// +optional
+	BaseRef string `json:"baseRef,omitempty" protobuf:"bytes,5,opt,name=baseRef"`
 }
 
 // Stage defines stage of pipe
 type Stage struct {
 	// Name defines stage name
 	Name string `json:"name" protobuf:"bytes,1,opt,name=name"`
-	// Action defines action from mario
+	// Action defines action from mario, it defaults to name of stage
+	// +optional
 	Action string `json:"action" protobuf:"bytes,2,opt,name=action"`
+	// DependsOn defines names of stages which must be completed before
+	// this stage starts.
+	// If no stage of a pipe sets it, stages will be run one by one,
+	// otherwise stages without dependencies will be started at once.
+	// +optional
+	DependsOn []string `json:"dependsOn,omitempty" protobuf:"bytes,3,rep,name=dependsOn"`
+	// Timeout defines max running duration of the stage job,
+	// it overrides timeout of the action
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty" protobuf:"bytes,4,opt,name=timeout"`
+	// When defines conditions of the event which triggers the flow,
+	// the stage will be skipped if they are not matched.
+	// Stages depending on a skipped stage will still be run.
+	// +optional
+	When *StageCondition `json:"when,omitempty" protobuf:"bytes,5,opt,name=when"`
+	// Matrix defines parameters of stage, one job will be generated for
+	// each combination of parameter values and parameters will be
+	// injected as env.
+	// Stage is completed only when all of its jobs are completed
+	// +optional
+	Matrix []MatrixParameter `json:"matrix,omitempty" protobuf:"bytes,6,rep,name=matrix"`
+	// PodOptions defines options of stage pod,
+	// it overrides options of the action
+	PodOptions `json:",inline" protobuf:"bytes,7,opt,name=podOptions"`
+}
+
+// MatrixParameter defines a parameter of stage matrix
+type MatrixParameter struct {
+	// Name defines name of parameter, it is also the env name
+	Name string `json:"name" protobuf:"bytes,1,opt,name=name"`
+	// Values defines all values of parameter
+	// +kubebuilder:validation:MinItems=1
+	Values []string `json:"values" protobuf:"bytes,2,rep,name=values"`
+}
+
+// StageCondition defines conditions of running a stage.
+// All of the specified fields must be matched, and a list is matched
+// if any item of it is matched.
+// Patterns are shell file name patterns, e.g. release-*
+type StageCondition struct {
+	// Refs defines patterns of git ref, e.g. refs/heads/main
+	// +optional
+	Refs []string `json:"refs,omitempty" protobuf:"bytes,1,rep,name=refs"`
+	// Branches defines patterns of branch name.
+	// Ref without refs/ prefix is also regarded as a branch name
+	// +optional
+	Branches []string `json:"branches,omitempty" protobuf:"bytes,2,rep,name=branches"`
+	// Tags defines patterns of tag name
+	// +optional
+	Tags []string `json:"tags,omitempty" protobuf:"bytes,3,rep,name=tags"`
+	// Extra defines expected values of event extra info
+	// +optional
+	Extra map[string]string `json:"extra,omitempty" protobuf:"bytes,4,rep,name=extra"`
 }
 
 const (
@@ -215,6 +603,10 @@ const (
 	// DefaultFlowStageLabelKey defines label key of flow stage label
 	DefaultFlowStageLabelKey = "flow.oooops.com/stage"
 
+	// DefaultFlowTokenLabelKey defines label key of mario token secret,
+	// value of the label is name of flow
+	DefaultFlowTokenLabelKey = "flow.oooops.com/token"
+
 	FlowStageGit   = "git"
 	FlowStageMario = "mario"
 )
@@ -262,17 +654,54 @@ type FlowSpec struct {
 	// Stages defines stages of flow
 	// +optional
 	Stages []Stage `json:"stages,omitempty" protobuf:"bytes,4,rep,name=stages"`
+
+	// Cancel means flow is cancelled, no more jobs will be created and
+	// running jobs will be deleted
+	// +optional
+	Cancel bool `json:"cancel,omitempty" protobuf:"varint,5,opt,name=cancel"`
+
+	// RetryFrom defines name of stage which will be run again, jobs of
+	// the stage and stages depending on it will be deleted and regenerated.
+	// It will be reset after jobs are deleted.
+	// +optional
+	RetryFrom string `json:"retryFrom,omitempty" protobuf:"bytes,6,opt,name=retryFrom"`
+
+	// Timeout defines max running duration of the whole flow from its creation,
+	// it also limits running duration of git and mario jobs
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty" protobuf:"bytes,7,opt,name=timeout"`
+
+	// Extra defines extra info of the event which triggers the flow,
+	// it is used to evaluate conditions of stages
+	// +optional
+	Extra map[string]string `json:"extra,omitempty" protobuf:"bytes,8,rep,name=extra"`
+
+	// AllowedSecrets defines names of secrets which can be mounted by actions
+	// +optional
+	AllowedSecrets []string `json:"allowedSecrets,omitempty" protobuf:"bytes,9,rep,name=allowedSecrets"`
+
+	// ConcurrencyPolicy defines how to deal with concurrent flows in the same group
+	// +optional
+	ConcurrencyPolicy ConcurrencyPolicy `json:"concurrencyPolicy,omitempty" protobuf:"bytes,10,opt,name=concurrencyPolicy"`
+
+	// ConcurrencyGroup defines key of concurrency group of flow
+	// +optional
+	ConcurrencyGroup string `json:"concurrencyGroup,omitempty" protobuf:"bytes,11,opt,name=concurrencyGroup"`
 }
 
 const (
 	// FlowPending means flow is pending
 	FlowPending = "Pending"
+	// FlowQueued means flow is waiting for earlier flows in the same concurrency group
+	FlowQueued = "Queued"
 	// FlowRunning means flow is running
 	FlowRunning = "Running"
 	// FlowSucceed means flow has succeeded
 	FlowSucceed = "Succeeded"
 	// FlowFailed means flow has failed
 	FlowFailed = "Failed"
+	// FlowCancelled means flow is cancelled before it is finished
+	FlowCancelled = "Cancelled"
 )
 
 // FlowStatus defines status of flow
@@ -284,6 +713,14 @@ type FlowStatus struct {
 	StageStatuses []StageStatus `json:"stageStatuses,omitempty" protobuf:"bytes,2,rep,name=stageStatuses"`
 	// Conditions defines condition of flow
 	Conditions []FlowCondition `json:"conditions,omitempty" protobuf:"bytes,3,rep,name=conditions"`
+	// CompletionTime defines time when flow is finished
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty" protobuf:"bytes,4,opt,name=completionTime"`
+	// StartTime defines time when flow starts to run, time spent in queue
+	// is not included. Timeout of flow is measured from it and it is reset
+	// when flow is retried
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty" protobuf:"bytes,5,opt,name=startTime"`
 }
 
 // FlowConditionType defines type of flow condition
@@ -295,6 +732,15 @@ const (
 
 	// FlowMarioReady means repo code is fetched and mario is attached
 	FlowMarioReady FlowConditionType = "MarioReady"
+
+	// FlowStagesResolved means dependencies of stages can be resolved
+	FlowStagesResolved FlowConditionType = "StagesResolved"
+
+	// FlowTimedOut means flow or some stages of flow exceed the deadline
+	FlowTimedOut FlowConditionType = "TimedOut"
+
+	// FlowInvalid means flow can't pass validation and will not be run
+	FlowInvalid FlowConditionType = "Invalid"
 )
 
 const (
@@ -313,6 +759,8 @@ const (
 	FlowReasonMarioFailed = "MarioFailed"
 	// FlowReasonMarioPending means mario is waiting for attching
 	FlowReasonMarioPending = "MarioPending"
+	// FlowReasonInvalidMario means mario fetched from repo can't pass validation
+	FlowReasonInvalidMario = "InvalidMario"
 	// FlowReasonMarioReady means mario is ready
 	FlowReasonMarioReady = "MarioReady"
 
@@ -320,6 +768,23 @@ const (
 	FlowReasonGitFailed = "GitFailed"
 	// FlowReasonGitPending means git job is waiting for starting
 	FlowReasonGitPending = "GitPending"
+
+	// FlowReasonStagesResolved means stages are resolved into a DAG
+	FlowReasonStagesResolved = "StagesResolved"
+	// FlowReasonInvalidDependency means some stages have invalid dependencies
+	// e.g. unknown stage or cycle
+	FlowReasonInvalidDependency = "InvalidDependency"
+
+	// FlowReasonInvalidRetry means stage to retry is not found or flow can't be retried
+	FlowReasonInvalidRetry = "InvalidRetry"
+
+	// FlowReasonInvalidSpec means spec of flow can't pass validation
+	FlowReasonInvalidSpec = "InvalidSpec"
+
+	// FlowReasonDeadlineExceeded means flow runs longer than its timeout
+	FlowReasonDeadlineExceeded = "DeadlineExceeded"
+	// FlowReasonStageDeadlineExceeded means a stage runs longer than its timeout
+	FlowReasonStageDeadlineExceeded = "StageDeadlineExceeded"
 )
 
 // FlowCondition defines condition of flow
@@ -352,6 +817,24 @@ const (
 	StageJobFailed = "JobFailed"
 	// StageJobRunning means job is running
 	StageJobRunning = "JobRunning"
+	// StageJobCancelled means job is cancelled before it is finished
+	StageJobCancelled = "JobCancelled"
+	// StageJobTimedOut means job is failed because it exceeds the deadline
+	StageJobTimedOut = "JobTimedOut"
+	// StageSkipped means stage is skipped because its conditions are not matched
+	StageSkipped = "Skipped"
+)
+
+const (
+	// StageReasonSecretNotAllowed means secret of action is not allowed by pipe
+	StageReasonSecretNotAllowed = "SecretNotAllowed"
+	// StageReasonSecretNotFound means secret of action is not found
+	StageReasonSecretNotFound = "SecretNotFound"
+	// StageReasonPolicyViolated means pod options of stage violate pod policy of namespace
+	StageReasonPolicyViolated = "PolicyViolated"
+	// StageReasonActionInvalid means template of action can't be resolved,
+	// e.g. imported action is not found or args of action are invalid
+	StageReasonActionInvalid = "ActionInvalid"
 )
 
 // StageStatus means status of each stage of flow
@@ -360,10 +843,59 @@ type StageStatus struct {
 	Job string `json:"job,omitempty" protobuf:"bytes,1,opt,name=job"`
 	// Phase of stage
 	Phase string `json:"phase,omitempty" protobuf:"bytes,2,opt,name=phase"`
+	// Name of stage
+	Name string `json:"name,omitempty" protobuf:"bytes,3,opt,name=name"`
+	// Attempts of stage which have been retried
+	// +optional
+	Attempts []StageAttempt `json:"attempts,omitempty" protobuf:"bytes,4,rep,name=attempts"`
+	// Legs defines status of jobs expanded from stage matrix,
+	// Job is empty if stage has a matrix
+	// +optional
+	Legs []StageLeg `json:"legs,omitempty" protobuf:"bytes,5,rep,name=legs"`
+	// Reason is a brief CamelCase string that describes why stage is failed
+	// before its job is created
+	// +optional
+	Reason string `json:"reason,omitempty" protobuf:"bytes,6,opt,name=reason"`
+	// Message is a human readable message indicating details about the reason
+	// +optional
+	Message string `json:"message,omitempty" protobuf:"bytes,7,opt,name=message"`
+}
+
+// StageLeg records status of a job expanded from stage matrix
+type StageLeg struct {
+	// Job of the leg
+	Job string `json:"job,omitempty" protobuf:"bytes,1,opt,name=job"`
+	// Phase of the leg
+	Phase string `json:"phase,omitempty" protobuf:"bytes,2,opt,name=phase"`
+	// Parameters defines parameter values of the leg
+	Parameters map[string]string `json:"parameters,omitempty" protobuf:"bytes,3,rep,name=parameters"`
+}
+
+// StageAttempt records a previous attempt of stage
+type StageAttempt struct {
+	// Job of the attempt
+	Job string `json:"job,omitempty" protobuf:"bytes,1,opt,name=job"`
+	// Phase of the attempt when it is retried
+	Phase string `json:"phase,omitempty" protobuf:"bytes,2,opt,name=phase"`
+	// StartTime is the creation time of job
+	StartTime metav1.Time `json:"startTime,omitempty" protobuf:"bytes,3,opt,name=startTime"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
+// ActionList defines list of action
+type ActionList struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ListMeta `json:"metadata,omitempty" protobuf:"bytes,1,opt,name=metadata"`
+
+	// Items defines an array of action
+	Items []Action `json:"items" protobuf:"bytes,2,rep,name=items"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
 // Action defines an external action which can be imported by mario
 type Action struct {
 	metav1.TypeMeta `json:",inline"`
@@ -375,16 +907,28 @@ type Action struct {
 	Spec ActionSpec `json:"spec,omitempty" protobuf:"bytes,2,opt,name=spec"`
 }
 
+// ActionSpec defines spec of Action
 type ActionSpec struct {
+	// Template defines template of action, value of args can be referenced
+	// in image, command, args and workingDir by $(args.<name>)
 	Template *ActionTemplate `json:"template"`
 
-	Args []ActionArg `json:"args"`
+	// Args defines args which can be set by mario action
+	// +optional
+	Args []ActionArg `json:"args,omitempty"`
 }
 
+// ActionArg defines arg of imported action
 type ActionArg struct {
-	Name        string `json:"name"`
-	Optional    bool   `json:"optional"`
-	Description string `json:"description"`
+	// Name defines name of arg
+	Name string `json:"name"`
+	// Optional means arg can be unset, unset optional arg will be
+	// replaced by empty string
+	// +optional
+	Optional bool `json:"optional,omitempty"`
+	// Description defines description of arg
+	// +optional
+	Description string `json:"description,omitempty"`
 }
 
 type MarioAction struct {
@@ -395,9 +939,22 @@ type MarioAction struct {
 
 	Env []ActionEnvVar `json:"envs,omitempty" protobuf:"rep,3,opt,name=envs"`
 
+	// Secrets defines secrets which will be mounted into the action pod,
+	// only secrets allowed by the pipe can be mounted
+	// +optional
 	Secrets []ActionSecret `json:"secrets,omitempty" protobuf:"rep,4,opt,name=version"`
 
-	ServiceAccountName string `json:"serviceAccountName,omitempty" protobuf:"bytes,5,opt,name=serviceAccountName"`
+	// PodOptions defines options of action pod,
+	// it overrides options of action template
+	PodOptions `json:",inline" protobuf:"bytes,5,opt,name=podOptions"`
+
+	// Args defines values of args of imported action
+	// +optional
+	Args map[string]string `json:"args,omitempty" protobuf:"bytes,6,rep,name=args"`
+
+	// Timeout defines max running duration of the action
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty" protobuf:"bytes,7,opt,name=timeout"`
 }
 
 type ActionTemplate struct {
@@ -415,6 +972,32 @@ type ActionTemplate struct {
 	// Version defines info of git version
 	// +optional
 	Version VersionDefinition `json:"version,omitempty" protobuf:"bytes,6,opt,name=version"`
+
+	// PodOptions defines default options of action pod
+	PodOptions `json:",inline" protobuf:"bytes,7,opt,name=podOptions"`
+}
+
+// PodOptions defines options of action pod.
+// Options are validated against the pod policy of namespace
+type PodOptions struct {
+	// ServiceAccountName defines name of service account to run action pod
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty" protobuf:"bytes,1,opt,name=serviceAccountName"`
+	// Resources defines compute resources of action container
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty" protobuf:"bytes,2,opt,name=resources"`
+	// NodeSelector defines node selector of action pod
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty" protobuf:"bytes,3,rep,name=nodeSelector"`
+	// Tolerations defines tolerations of action pod
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty" protobuf:"bytes,4,rep,name=tolerations"`
+	// Affinity defines scheduling constraints of action pod
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty" protobuf:"bytes,5,opt,name=affinity"`
+	// SecurityContext defines security options of action container
+	// +optional
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty" protobuf:"bytes,6,opt,name=securityContext"`
 }
 
 // ActionEnvVar defines env variable of action
@@ -423,7 +1006,10 @@ type ActionEnvVar struct {
 	Value string `json:"value"`
 }
 
+// ActionSecret defines secret which will be mounted into action pod
 type ActionSecret struct {
-	Name      string `json:"name"`
+	// Name defines name of secret in the namespace of flow
+	Name string `json:"name"`
+	// MountPath defines path to mount the secret
 	MountPath string `json:"mountPath"`
 }
@@ -85,6 +85,39 @@ func (in *ActionEnvVar) DeepCopy() *ActionEnvVar {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActionList) DeepCopyInto(out *ActionList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Action, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionList.
+func (in *ActionList) DeepCopy() *ActionList {
+	if in == nil {
+		return nil
+	}
+	out := new(ActionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ActionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ActionSecret) DeepCopyInto(out *ActionSecret) {
 	*out = *in
@@ -141,6 +174,7 @@ func (in *ActionTemplate) DeepCopyInto(out *ActionTemplate) {
 		copy(*out, *in)
 	}
 	out.Version = in.Version
+	in.PodOptions.DeepCopyInto(&out.PodOptions)
 	return
 }
 
@@ -160,6 +194,7 @@ func (in *Event) DeepCopyInto(out *Event) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -214,6 +249,22 @@ func (in *EventList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EventPipeStatus) DeepCopyInto(out *EventPipeStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EventPipeStatus.
+func (in *EventPipeStatus) DeepCopy() *EventPipeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(EventPipeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EventSpec) DeepCopyInto(out *EventSpec) {
 	*out = *in
@@ -224,6 +275,11 @@ func (in *EventSpec) DeepCopyInto(out *EventSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.ChangedFiles != nil {
+		in, out := &in.ChangedFiles, &out.ChangedFiles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -237,6 +293,31 @@ func (in *EventSpec) DeepCopy() *EventSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EventStatus) DeepCopyInto(out *EventStatus) {
+	*out = *in
+	if in.Pipes != nil {
+		in, out := &in.Pipes, &out.Pipes
+		*out = make([]EventPipeStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EventStatus.
+func (in *EventStatus) DeepCopy() *EventStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(EventStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Flow) DeepCopyInto(out *Flow) {
 	*out = *in
@@ -333,6 +414,25 @@ func (in *FlowSpec) DeepCopyInto(out *FlowSpec) {
 	if in.Stages != nil {
 		in, out := &in.Stages, &out.Stages
 		*out = make([]Stage, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Extra != nil {
+		in, out := &in.Extra, &out.Extra
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.AllowedSecrets != nil {
+		in, out := &in.AllowedSecrets, &out.AllowedSecrets
+		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
 	return
@@ -354,7 +454,9 @@ func (in *FlowStatus) DeepCopyInto(out *FlowStatus) {
 	if in.StageStatuses != nil {
 		in, out := &in.StageStatuses, &out.StageStatuses
 		*out = make([]StageStatus, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
@@ -363,6 +465,14 @@ func (in *FlowStatus) DeepCopyInto(out *FlowStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
@@ -443,6 +553,19 @@ func (in *MarioAction) DeepCopyInto(out *MarioAction) {
 		*out = make([]ActionSecret, len(*in))
 		copy(*out, *in)
 	}
+	in.PodOptions.DeepCopyInto(&out.PodOptions)
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
 	return
 }
 
@@ -517,13 +640,60 @@ func (in *MarioSpec) DeepCopy() *MarioSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MatrixParameter) DeepCopyInto(out *MatrixParameter) {
+	*out = *in
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MatrixParameter.
+func (in *MatrixParameter) DeepCopy() *MatrixParameter {
+	if in == nil {
+		return nil
+	}
+	out := new(MatrixParameter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PatternFilter) DeepCopyInto(out *PatternFilter) {
+	*out = *in
+	if in.Include != nil {
+		in, out := &in.Include, &out.Include
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Exclude != nil {
+		in, out := &in.Exclude, &out.Exclude
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PatternFilter.
+func (in *PatternFilter) DeepCopy() *PatternFilter {
+	if in == nil {
+		return nil
+	}
+	out := new(PatternFilter)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Pipe) DeepCopyInto(out *Pipe) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -545,6 +715,39 @@ func (in *Pipe) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipeCondition) DeepCopyInto(out *PipeCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipeCondition.
+func (in *PipeCondition) DeepCopy() *PipeCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(PipeCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipeFlowStatus) DeepCopyInto(out *PipeFlowStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipeFlowStatus.
+func (in *PipeFlowStatus) DeepCopy() *PipeFlowStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PipeFlowStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PipeList) DeepCopyInto(out *PipeList) {
 	*out = *in
@@ -578,6 +781,27 @@ func (in *PipeList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipeSchedule) DeepCopyInto(out *PipeSchedule) {
+	*out = *in
+	if in.StartingDeadlineSeconds != nil {
+		in, out := &in.StartingDeadlineSeconds, &out.StartingDeadlineSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipeSchedule.
+func (in *PipeSchedule) DeepCopy() *PipeSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(PipeSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PipeSpec) DeepCopyInto(out *PipeSpec) {
 	*out = *in
@@ -595,8 +819,60 @@ func (in *PipeSpec) DeepCopyInto(out *PipeSpec) {
 	if in.Stages != nil {
 		in, out := &in.Stages, &out.Stages
 		*out = make([]Stage, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.AllowedSecrets != nil {
+		in, out := &in.AllowedSecrets, &out.AllowedSecrets
+		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.PullRequest != nil {
+		in, out := &in.PullRequest, &out.PullRequest
+		*out = new(PullRequestOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Schedule != nil {
+		in, out := &in.Schedule, &out.Schedule
+		*out = new(PipeSchedule)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Branches != nil {
+		in, out := &in.Branches, &out.Branches
+		*out = new(PatternFilter)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = new(PatternFilter)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Paths != nil {
+		in, out := &in.Paths, &out.Paths
+		*out = new(PatternFilter)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SuccessfulFlowsHistoryLimit != nil {
+		in, out := &in.SuccessfulFlowsHistoryLimit, &out.SuccessfulFlowsHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.FailedFlowsHistoryLimit != nil {
+		in, out := &in.FailedFlowsHistoryLimit, &out.FailedFlowsHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.FlowTTLSecondsAfterFinished != nil {
+		in, out := &in.FlowTTLSecondsAfterFinished, &out.FlowTTLSecondsAfterFinished
+		*out = new(int32)
+		**out = **in
+	}
 	return
 }
 
@@ -613,6 +889,22 @@ func (in *PipeSpec) DeepCopy() *PipeSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PipeStatus) DeepCopyInto(out *PipeStatus) {
 	*out = *in
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastFlow != nil {
+		in, out := &in.LastFlow, &out.LastFlow
+		*out = new(PipeFlowStatus)
+		**out = **in
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]PipeCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -626,9 +918,98 @@ func (in *PipeStatus) DeepCopy() *PipeStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodOptions) DeepCopyInto(out *PodOptions) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(corev1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SecurityContext != nil {
+		in, out := &in.SecurityContext, &out.SecurityContext
+		*out = new(corev1.SecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodOptions.
+func (in *PodOptions) DeepCopy() *PodOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(PodOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PullRequestOptions) DeepCopyInto(out *PullRequestOptions) {
+	*out = *in
+	if in.Branches != nil {
+		in, out := &in.Branches, &out.Branches
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PullRequestOptions.
+func (in *PullRequestOptions) DeepCopy() *PullRequestOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(PullRequestOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Stage) DeepCopyInto(out *Stage) {
 	*out = *in
+	if in.DependsOn != nil {
+		in, out := &in.DependsOn, &out.DependsOn
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.When != nil {
+		in, out := &in.When, &out.When
+		*out = new(StageCondition)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Matrix != nil {
+		in, out := &in.Matrix, &out.Matrix
+		*out = make([]MatrixParameter, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.PodOptions.DeepCopyInto(&out.PodOptions)
 	return
 }
 
@@ -642,9 +1023,101 @@ func (in *Stage) DeepCopy() *Stage {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StageAttempt) DeepCopyInto(out *StageAttempt) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StageAttempt.
+func (in *StageAttempt) DeepCopy() *StageAttempt {
+	if in == nil {
+		return nil
+	}
+	out := new(StageAttempt)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StageCondition) DeepCopyInto(out *StageCondition) {
+	*out = *in
+	if in.Refs != nil {
+		in, out := &in.Refs, &out.Refs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Branches != nil {
+		in, out := &in.Branches, &out.Branches
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Extra != nil {
+		in, out := &in.Extra, &out.Extra
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StageCondition.
+func (in *StageCondition) DeepCopy() *StageCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(StageCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StageLeg) DeepCopyInto(out *StageLeg) {
+	*out = *in
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StageLeg.
+func (in *StageLeg) DeepCopy() *StageLeg {
+	if in == nil {
+		return nil
+	}
+	out := new(StageLeg)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StageStatus) DeepCopyInto(out *StageStatus) {
 	*out = *in
+	if in.Attempts != nil {
+		in, out := &in.Attempts, &out.Attempts
+		*out = make([]StageAttempt, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Legs != nil {
+		in, out := &in.Legs, &out.Legs
+		*out = make([]StageLeg, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -28,5 +28,38 @@ import (
 // Public to allow building arbitrary schemes.
 // All generated defaulters are covering - they call all nested defaulters.
 func RegisterDefaults(scheme *runtime.Scheme) error {
+	scheme.AddTypeDefaultingFunc(&Flow{}, func(obj interface{}) { SetObjectDefaults_Flow(obj.(*Flow)) })
+	scheme.AddTypeDefaultingFunc(&FlowList{}, func(obj interface{}) { SetObjectDefaults_FlowList(obj.(*FlowList)) })
+	scheme.AddTypeDefaultingFunc(&Pipe{}, func(obj interface{}) { SetObjectDefaults_Pipe(obj.(*Pipe)) })
+	scheme.AddTypeDefaultingFunc(&PipeList{}, func(obj interface{}) { SetObjectDefaults_PipeList(obj.(*PipeList)) })
 	return nil
 }
+
+func SetObjectDefaults_Flow(in *Flow) {
+	for i := range in.Spec.Stages {
+		a := &in.Spec.Stages[i]
+		SetDefaults_Stage(a)
+	}
+}
+
+func SetObjectDefaults_FlowList(in *FlowList) {
+	for i := range in.Items {
+		a := &in.Items[i]
+		SetObjectDefaults_Flow(a)
+	}
+}
+
+func SetObjectDefaults_Pipe(in *Pipe) {
+	SetDefaults_Pipe(in)
+	for i := range in.Spec.Stages {
+		a := &in.Spec.Stages[i]
+		SetDefaults_Stage(a)
+	}
+}
+
+func SetObjectDefaults_PipeList(in *PipeList) {
+	for i := range in.Items {
+		a := &in.Items[i]
+		SetObjectDefaults_Pipe(a)
+	}
+}
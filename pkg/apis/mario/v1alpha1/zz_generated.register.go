@@ -59,6 +59,7 @@ func init() {
 func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&Action{},
+		&ActionList{},
 		&Event{},
 		&EventList{},
 		&Flow{},
@@ -0,0 +1,517 @@
+// Package validation defines validation of mario objects, it is shared by
+// the admission webhook and controllers
+package validation
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	"github.com/liubog2008/oooops/pkg/mario/template"
+	"github.com/liubog2008/oooops/pkg/utils/cron"
+)
+
+const (
+	// FlowNameSuffixLength defines length of random suffix of flow generated by pipe
+	FlowNameSuffixLength = 7
+	// LegSuffixLength defines length of suffix of matrix job name
+	LegSuffixLength = 5
+)
+
+var (
+	supportedWhen = sets.NewString(
+		string(v1alpha1.Push),
+		string(v1alpha1.Tag),
+		string(v1alpha1.PullRequest),
+		string(v1alpha1.Schedule),
+	)
+
+	supportedConcurrencyPolicies = sets.NewString(
+		"",
+		string(v1alpha1.AllowConcurrent),
+		string(v1alpha1.ForbidConcurrent),
+		string(v1alpha1.ReplaceConcurrent),
+	)
+
+	supportedCheckouts = sets.NewString(
+		"",
+		string(v1alpha1.PullRequestCheckoutHead),
+		string(v1alpha1.PullRequestCheckoutMerge),
+	)
+)
+
+// StageJobName returns name of job of stage in flow,
+// suffix is empty if stage has no matrix
+func StageJobName(flowName, stageName, suffix string) string {
+	if suffix == "" {
+		return strings.Join([]string{flowName, "user", stageName}, "-")
+	}
+	return strings.Join([]string{flowName, "user", stageName, suffix}, "-")
+}
+
+// LegSuffix generates a stable suffix of matrix job name from parameters,
+// it is not changed if order of parameters is changed
+func LegSuffix(params []corev1.EnvVar) string {
+	pairs := make([]string, 0, len(params))
+	for _, p := range params {
+		pairs = append(pairs, p.Name+"="+p.Value)
+	}
+	sort.Strings(pairs)
+
+	hasher := md5.New()
+	hasher.Write([]byte(strings.Join(pairs, ",")))
+	code := hex.EncodeToString(hasher.Sum(nil))
+	return code[:LegSuffixLength]
+}
+
+// ValidatePipe validates pipe
+func ValidatePipe(pipe *v1alpha1.Pipe) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	// flow name is generated from pipe name and a random suffix
+	flowName := ""
+	if pipe.Name != "" {
+		flowName = pipe.Name + "-" + strings.Repeat("x", FlowNameSuffixLength)
+		allErrs = append(allErrs, validateFlowName(flowName, field.NewPath("metadata", "name"))...)
+	}
+
+	allErrs = append(allErrs, ValidatePipeSpec(&pipe.Spec, flowName, field.NewPath("spec"))...)
+	return allErrs
+}
+
+// ValidatePipeSpec validates spec of pipe, job names are not
+// validated if flow name is empty
+func ValidatePipeSpec(spec *v1alpha1.PipeSpec, flowName string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if spec.Selector != nil {
+		allErrs = append(allErrs, validateSelector(spec.Selector, fldPath.Child("selector"))...)
+	}
+
+	scheduled := false
+	for i, when := range spec.When {
+		if !supportedWhen.Has(string(when)) {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("when").Index(i), when, supportedWhen.List()))
+		}
+		if when == v1alpha1.Schedule {
+			scheduled = true
+		}
+	}
+
+	allErrs = append(allErrs, validateGit(&spec.Git, fldPath.Child("git"))...)
+	allErrs = append(allErrs, ValidateStages(spec.Stages, flowName, fldPath.Child("stages"))...)
+	allErrs = append(allErrs, validateTimeout(spec.Timeout, fldPath.Child("timeout"))...)
+	allErrs = append(allErrs, validateSecretNames(spec.AllowedSecrets, fldPath.Child("allowedSecrets"))...)
+
+	if spec.PullRequest != nil && !supportedCheckouts.Has(string(spec.PullRequest.Checkout)) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("pullRequest", "checkout"),
+			spec.PullRequest.Checkout, supportedCheckouts.List()))
+	}
+
+	if scheduled && spec.Schedule == nil {
+		allErrs = append(allErrs, field.Required(fldPath.Child("schedule"), "schedule is required when pipe is triggered by schedule"))
+	}
+	if spec.Schedule != nil {
+		allErrs = append(allErrs, validateSchedule(spec.Schedule, fldPath.Child("schedule"))...)
+	}
+
+	allErrs = append(allErrs, validatePatternFilter(spec.Branches, fldPath.Child("branches"))...)
+	allErrs = append(allErrs, validatePatternFilter(spec.Tags, fldPath.Child("tags"))...)
+	allErrs = append(allErrs, validatePatternFilter(spec.Paths, fldPath.Child("paths"))...)
+
+	if !supportedConcurrencyPolicies.Has(string(spec.ConcurrencyPolicy)) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("concurrencyPolicy"),
+			spec.ConcurrencyPolicy, supportedConcurrencyPolicies.List()))
+	}
+
+	allErrs = append(allErrs, validateNonNegative(spec.SuccessfulFlowsHistoryLimit, fldPath.Child("successfulFlowsHistoryLimit"))...)
+	allErrs = append(allErrs, validateNonNegative(spec.FailedFlowsHistoryLimit, fldPath.Child("failedFlowsHistoryLimit"))...)
+	allErrs = append(allErrs, validateNonNegative(spec.FlowTTLSecondsAfterFinished, fldPath.Child("flowTTLSecondsAfterFinished"))...)
+
+	return allErrs
+}
+
+// ValidateFlow validates flow
+func ValidateFlow(flow *v1alpha1.Flow) field.ErrorList {
+	allErrs := field.ErrorList{}
+	fldPath := field.NewPath("spec")
+
+	if flow.Name != "" {
+		allErrs = append(allErrs, validateFlowName(flow.Name, field.NewPath("metadata", "name"))...)
+	}
+
+	spec := &flow.Spec
+	if spec.Selector == nil {
+		allErrs = append(allErrs, field.Required(fldPath.Child("selector"), ""))
+	} else {
+		allErrs = append(allErrs, validateSelector(spec.Selector, fldPath.Child("selector"))...)
+	}
+
+	if spec.Mario != nil {
+		allErrs = append(allErrs, ValidateMarioSpec(&spec.Mario.Spec, fldPath.Child("mario", "spec"))...)
+	}
+
+	allErrs = append(allErrs, validateGit(&spec.Git, fldPath.Child("git"))...)
+	allErrs = append(allErrs, ValidateStages(spec.Stages, flow.Name, fldPath.Child("stages"))...)
+	allErrs = append(allErrs, validateTimeout(spec.Timeout, fldPath.Child("timeout"))...)
+	allErrs = append(allErrs, validateSecretNames(spec.AllowedSecrets, fldPath.Child("allowedSecrets"))...)
+
+	if !supportedConcurrencyPolicies.Has(string(spec.ConcurrencyPolicy)) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("concurrencyPolicy"),
+			spec.ConcurrencyPolicy, supportedConcurrencyPolicies.List()))
+	}
+
+	return allErrs
+}
+
+// ValidateMario validates mario
+func ValidateMario(mario *v1alpha1.Mario) field.ErrorList {
+	return ValidateMarioSpec(&mario.Spec, field.NewPath("spec"))
+}
+
+// ValidateMarioSpec validates spec of mario
+func ValidateMarioSpec(spec *v1alpha1.MarioSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	for i, p := range spec.Imports {
+		idxPath := fldPath.Child("imports").Index(i)
+		if strings.Contains(p, "/") {
+			allErrs = append(allErrs, field.Invalid(idxPath, p, "actions of other namespaces can't be imported"))
+			continue
+		}
+		for _, msg := range validation.IsDNS1123Subdomain(p) {
+			allErrs = append(allErrs, field.Invalid(idxPath, p, msg))
+		}
+	}
+
+	names := sets.NewString()
+	for i := range spec.Actions {
+		action := &spec.Actions[i]
+		idxPath := fldPath.Child("actions").Index(i)
+
+		switch {
+		case action.Name == "":
+			allErrs = append(allErrs, field.Required(idxPath.Child("name"), ""))
+		case strings.HasPrefix(action.Name, v1alpha1.SystemActionPrefix):
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("name"), action.Name,
+				fmt.Sprintf("prefix %s is reserved for system actions", v1alpha1.SystemActionPrefix)))
+		case names.Has(action.Name):
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), action.Name))
+		}
+		names.Insert(action.Name)
+
+		envs := sets.NewString()
+		for j := range action.Env {
+			env := &action.Env[j]
+			envPath := idxPath.Child("envs").Index(j)
+			for _, msg := range validation.IsEnvVarName(env.Name) {
+				allErrs = append(allErrs, field.Invalid(envPath.Child("name"), env.Name, msg))
+			}
+			if envs.Has(env.Name) {
+				allErrs = append(allErrs, field.Duplicate(envPath.Child("name"), env.Name))
+			}
+			envs.Insert(env.Name)
+		}
+
+		mountPaths := sets.NewString()
+		for j := range action.Secrets {
+			secret := &action.Secrets[j]
+			secretPath := idxPath.Child("secrets").Index(j)
+			for _, msg := range validation.IsDNS1123Subdomain(secret.Name) {
+				allErrs = append(allErrs, field.Invalid(secretPath.Child("name"), secret.Name, msg))
+			}
+			switch {
+			case !path.IsAbs(secret.MountPath):
+				allErrs = append(allErrs, field.Invalid(secretPath.Child("mountPath"), secret.MountPath, "must be an absolute path"))
+			case mountPaths.Has(secret.MountPath):
+				allErrs = append(allErrs, field.Duplicate(secretPath.Child("mountPath"), secret.MountPath))
+			}
+			mountPaths.Insert(secret.MountPath)
+		}
+
+		allErrs = append(allErrs, validateTimeout(action.Timeout, idxPath.Child("timeout"))...)
+	}
+
+	return allErrs
+}
+
+// ValidateStages validates stages, names of stage jobs are validated
+// if flow name is not empty
+func ValidateStages(stages []v1alpha1.Stage, flowName string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	names := sets.NewString()
+	for i := range stages {
+		names.Insert(stages[i].Name)
+	}
+
+	seen := sets.NewString()
+	for i := range stages {
+		stage := &stages[i]
+		idxPath := fldPath.Index(i)
+
+		switch {
+		case stage.Name == "":
+			allErrs = append(allErrs, field.Required(idxPath.Child("name"), ""))
+		case seen.Has(stage.Name):
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), stage.Name))
+		default:
+			allErrs = append(allErrs, validateStageName(stage, flowName, idxPath.Child("name"))...)
+		}
+		seen.Insert(stage.Name)
+
+		if stage.Action == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("action"), ""))
+		}
+
+		for j, dep := range stage.DependsOn {
+			if !names.Has(dep) {
+				allErrs = append(allErrs, field.NotFound(idxPath.Child("dependsOn").Index(j), dep))
+			}
+		}
+
+		if err := ValidateStageCondition(stage.When); err != nil {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("when"), stage.When, err.Error()))
+		}
+		if err := ValidateMatrix(stage.Matrix); err != nil {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("matrix"), stage.Matrix, err.Error()))
+		} else if name, ok := findLegConflict(stage, names); ok {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("matrix"), stage.Matrix,
+				fmt.Sprintf("job of matrix leg is conflict with job of stage %s", name)))
+		}
+
+		allErrs = append(allErrs, validateTimeout(stage.Timeout, idxPath.Child("timeout"))...)
+	}
+
+	return allErrs
+}
+
+// ValidateStageCondition returns error if some patterns of condition are invalid
+func ValidateStageCondition(cond *v1alpha1.StageCondition) error {
+	if cond == nil {
+		return nil
+	}
+	for _, patterns := range [][]string{cond.Refs, cond.Branches, cond.Tags} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("pattern %q is invalid: %v", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateMatrix returns error if names of parameters are invalid env names,
+// duplicated or some parameters have no value
+func ValidateMatrix(matrix []v1alpha1.MatrixParameter) error {
+	names := map[string]struct{}{}
+	for i := range matrix {
+		p := &matrix[i]
+		if msgs := validation.IsEnvVarName(p.Name); len(msgs) != 0 {
+			return fmt.Errorf("matrix parameter name %s is invalid: %s", p.Name, strings.Join(msgs, "; "))
+		}
+		if _, ok := names[p.Name]; ok {
+			return fmt.Errorf("matrix parameter %s is duplicated", p.Name)
+		}
+		names[p.Name] = struct{}{}
+
+		if len(p.Values) == 0 {
+			return fmt.Errorf("matrix parameter %s has no value", p.Name)
+		}
+	}
+	return nil
+}
+
+// findLegConflict returns name of stage whose job is the same as
+// job of one of matrix legs of stage, e.g. stage build-1a2b3
+func findLegConflict(stage *v1alpha1.Stage, names sets.String) (string, bool) {
+	if len(stage.Matrix) == 0 {
+		return "", false
+	}
+	for _, params := range template.ExpandMatrix(stage.Matrix) {
+		name := stage.Name + "-" + LegSuffix(params)
+		if names.Has(name) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// validateStageName validates that stage name can be used in the name of stage job,
+// job name is also used as label value so it must be a DNS-1123 label
+func validateStageName(stage *v1alpha1.Stage, flowName string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for _, msg := range validation.IsDNS1123Label(stage.Name) {
+		allErrs = append(allErrs, field.Invalid(fldPath, stage.Name, msg))
+	}
+	if len(allErrs) != 0 || flowName == "" {
+		return allErrs
+	}
+
+	suffix := ""
+	if len(stage.Matrix) != 0 {
+		suffix = strings.Repeat("x", LegSuffixLength)
+	}
+	name := StageJobName(flowName, stage.Name, suffix)
+	for _, msg := range validation.IsDNS1123Label(name) {
+		allErrs = append(allErrs, field.Invalid(fldPath, stage.Name, fmt.Sprintf("job name %s is invalid: %s", name, msg)))
+	}
+	return allErrs
+}
+
+// validateFlowName validates that names of pvc and jobs generated from flow name are valid
+func validateFlowName(flowName string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for _, stage := range []string{v1alpha1.FlowStageGit, v1alpha1.FlowStageMario} {
+		name := flowName + "-" + stage
+		for _, msg := range validation.IsDNS1123Label(name) {
+			allErrs = append(allErrs, field.Invalid(fldPath, flowName, fmt.Sprintf("job name %s is invalid: %s", name, msg)))
+		}
+	}
+	return allErrs
+}
+
+func validateSelector(selector *metav1.LabelSelector, fldPath *field.Path) field.ErrorList {
+	allErrs := metav1validation.ValidateLabelSelector(selector, fldPath)
+	if len(allErrs) != 0 {
+		return allErrs
+	}
+	if _, err := metav1.LabelSelectorAsSelector(selector); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath, selector, err.Error()))
+	}
+	return allErrs
+}
+
+func validateGit(git *v1alpha1.Git, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if git.Repo == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("repo"), ""))
+	}
+	if git.VolumeClaimTemplate == nil {
+		allErrs = append(allErrs, field.Required(fldPath.Child("volumeClaimTemplate"), "volume is required to store git code"))
+	}
+	allErrs = append(allErrs, validateRef(git.Ref, fldPath.Child("ref"))...)
+	allErrs = append(allErrs, validateRef(git.BaseRef, fldPath.Child("baseRef"))...)
+	return allErrs
+}
+
+func validateRef(ref string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if ref == "" {
+		return allErrs
+	}
+	for _, msg := range isRef(ref) {
+		allErrs = append(allErrs, field.Invalid(fldPath, ref, msg))
+	}
+	return allErrs
+}
+
+// isRef tests for a string that conforms to the rules of git check-ref-format
+// with --allow-onelevel, and it can't start with a dash to be passed to git safely.
+func isRef(ref string) []string {
+	msgs := []string{}
+	if ref == "@" {
+		msgs = append(msgs, "must not be '@'")
+	}
+	if strings.HasPrefix(ref, "-") {
+		msgs = append(msgs, "must not start with '-'")
+	}
+	if strings.HasPrefix(ref, "/") || strings.HasSuffix(ref, "/") || strings.HasSuffix(ref, ".") {
+		msgs = append(msgs, "must not start with '/' or end with '/' or '.'")
+	}
+	for _, s := range []string{"//", "..", "@{"} {
+		if strings.Contains(ref, s) {
+			msgs = append(msgs, fmt.Sprintf("must not contain '%s'", s))
+		}
+	}
+	if strings.ContainsAny(ref, " ~^:?*[\\") {
+		msgs = append(msgs, "must not contain space or any of '~^:?*[\\'")
+	}
+	if strings.IndexFunc(ref, func(r rune) bool { return r < 0x20 || r == 0x7f }) != -1 {
+		msgs = append(msgs, "must not contain control characters")
+	}
+	for _, component := range strings.Split(ref, "/") {
+		if strings.HasPrefix(component, ".") || strings.HasSuffix(component, ".lock") {
+			msgs = append(msgs, "components must not start with '.' or end with '.lock'")
+			break
+		}
+	}
+	return msgs
+}
+
+func validateSchedule(schedule *v1alpha1.PipeSchedule, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if schedule.Ref == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("ref"), ""))
+	}
+	allErrs = append(allErrs, validateRef(schedule.Ref, fldPath.Child("ref"))...)
+	if _, err := cron.Parse(schedule.Cron); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("cron"), schedule.Cron, err.Error()))
+	}
+	if schedule.TimeZone != "" {
+		if _, err := time.LoadLocation(schedule.TimeZone); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("timeZone"), schedule.TimeZone, err.Error()))
+		}
+	}
+	if schedule.StartingDeadlineSeconds != nil && *schedule.StartingDeadlineSeconds < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("startingDeadlineSeconds"),
+			*schedule.StartingDeadlineSeconds, "must be greater than or equal to 0"))
+	}
+	return allErrs
+}
+
+func validatePatternFilter(filter *v1alpha1.PatternFilter, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if filter == nil {
+		return allErrs
+	}
+	for i, pattern := range filter.Include {
+		if _, err := path.Match(pattern, ""); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("include").Index(i), pattern, err.Error()))
+		}
+	}
+	for i, pattern := range filter.Exclude {
+		if _, err := path.Match(pattern, ""); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("exclude").Index(i), pattern, err.Error()))
+		}
+	}
+	return allErrs
+}
+
+func validateNonNegative(v *int32, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if v != nil && *v < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath, *v, "must be greater than or equal to 0"))
+	}
+	return allErrs
+}
+
+func validateTimeout(timeout *metav1.Duration, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if timeout != nil && timeout.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath, timeout.Duration.String(), "must be greater than 0"))
+	}
+	return allErrs
+}
+
+func validateSecretNames(names []string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for i, name := range names {
+		for _, msg := range validation.IsDNS1123Subdomain(name) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(i), name, msg))
+		}
+	}
+	return allErrs
+}
@@ -0,0 +1,179 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+func TestValidatePipe(t *testing.T) {
+	newPipe := func(name string, stages ...v1alpha1.Stage) *v1alpha1.Pipe {
+		return &v1alpha1.Pipe{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+			Spec: v1alpha1.PipeSpec{
+				When: []v1alpha1.When{v1alpha1.Push},
+				Git: v1alpha1.Git{
+					Repo:                "https://github.com/liubog2008/oooops",
+					VolumeClaimTemplate: &corev1.PersistentVolumeClaim{},
+				},
+				Stages: stages,
+			},
+		}
+	}
+	fields := func(errs field.ErrorList) []string {
+		s := []string{}
+		for _, err := range errs {
+			s = append(s, err.Field)
+		}
+		return s
+	}
+
+	assert.Empty(t, ValidatePipe(newPipe("ci",
+		v1alpha1.Stage{Name: "build", Action: "build"},
+		v1alpha1.Stage{Name: "test", Action: "test", DependsOn: []string{"build"}},
+	)))
+
+	dup := newPipe("ci",
+		v1alpha1.Stage{Name: "build", Action: "build"},
+		v1alpha1.Stage{Name: "build", Action: "test", DependsOn: []string{"lint"}},
+	)
+	assert.Equal(t, []string{"spec.stages[1].name", "spec.stages[1].dependsOn[0]"}, fields(ValidatePipe(dup)))
+
+	long := newPipe("ci", v1alpha1.Stage{Name: strings.Repeat("a", 50), Action: "build"})
+	assert.Equal(t, []string{"spec.stages[0].name"}, fields(ValidatePipe(long)))
+	long.Name = strings.Repeat("a", 60)
+	assert.Contains(t, fields(ValidatePipe(long)), "metadata.name")
+
+	upper := newPipe("ci", v1alpha1.Stage{Name: "Build", Action: "build"})
+	assert.Equal(t, []string{"spec.stages[0].name"}, fields(ValidatePipe(upper)))
+
+	noVolume := newPipe("ci")
+	noVolume.Spec.Git.VolumeClaimTemplate = nil
+	assert.Equal(t, []string{"spec.git.volumeClaimTemplate"}, fields(ValidatePipe(noVolume)))
+
+	badSelector := newPipe("ci")
+	badSelector.Spec.Selector = &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "app", Operator: metav1.LabelSelectorOpIn},
+		},
+	}
+	assert.Equal(t, []string{"spec.selector.matchExpressions[0].values"}, fields(ValidatePipe(badSelector)))
+
+	scheduled := newPipe("ci")
+	scheduled.Spec.When = append(scheduled.Spec.When, v1alpha1.Schedule, "git:unknown")
+	assert.Equal(t, []string{"spec.when[2]", "spec.schedule"}, fields(ValidatePipe(scheduled)))
+	scheduled.Spec.When = scheduled.Spec.When[:2]
+	scheduled.Spec.Schedule = &v1alpha1.PipeSchedule{Cron: "* * *", Ref: "refs/heads/master"}
+	assert.Equal(t, []string{"spec.schedule.cron"}, fields(ValidatePipe(scheduled)))
+}
+
+func TestValidateMario(t *testing.T) {
+	mario := &v1alpha1.Mario{
+		Spec: v1alpha1.MarioSpec{
+			Imports: []string{"build", "shared/deploy", "a/b/c"},
+			Actions: []v1alpha1.MarioAction{
+				{Name: "build", Env: []v1alpha1.ActionEnvVar{{Name: "A"}, {Name: "A"}}},
+				{Name: "build"},
+				{Name: v1alpha1.SystemActionPrefix + "deploy"},
+				{Name: "test", Secrets: []v1alpha1.ActionSecret{{Name: "token", MountPath: "token"}}},
+			},
+		},
+	}
+
+	errs := ValidateMario(mario)
+	fields := []string{}
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+	assert.Equal(t, []string{
+		"spec.imports[1]",
+		"spec.imports[2]",
+		"spec.actions[0].envs[1].name",
+		"spec.actions[1].name",
+		"spec.actions[2].name",
+		"spec.actions[3].secrets[0].mountPath",
+	}, fields)
+}
+
+func TestValidateMatrix(t *testing.T) {
+	assert.NoError(t, ValidateMatrix(nil))
+	assert.Error(t, ValidateMatrix([]v1alpha1.MatrixParameter{
+		{Name: "A", Values: []string{"1"}},
+		{Name: "A", Values: []string{"2"}},
+	}))
+	assert.Error(t, ValidateMatrix([]v1alpha1.MatrixParameter{
+		{Name: "A"},
+	}))
+	assert.Error(t, ValidateMatrix([]v1alpha1.MatrixParameter{
+		{Name: "GO=VERSION", Values: []string{"1.13"}},
+	}))
+	assert.Error(t, ValidateMatrix([]v1alpha1.MatrixParameter{
+		{Name: "1X", Values: []string{"1"}},
+	}))
+}
+
+func TestValidateStagesLegConflict(t *testing.T) {
+	matrix := []v1alpha1.MatrixParameter{{Name: "GO_VERSION", Values: []string{"1.13"}}}
+	suffix := LegSuffix([]corev1.EnvVar{{Name: "GO_VERSION", Value: "1.13"}})
+
+	stages := []v1alpha1.Stage{
+		{Name: "build", Action: "build", Matrix: matrix},
+		{Name: "test", Action: "test"},
+	}
+	assert.Empty(t, ValidateStages(stages, "", field.NewPath("stages")))
+
+	stages[1].Name = "build-" + suffix
+	errs := ValidateStages(stages, "", field.NewPath("stages"))
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "stages[0].matrix", errs[0].Field)
+	}
+}
+
+func TestValidateStageCondition(t *testing.T) {
+	assert.NoError(t, ValidateStageCondition(nil))
+	assert.NoError(t, ValidateStageCondition(&v1alpha1.StageCondition{Branches: []string{"release-*"}}))
+	assert.Error(t, ValidateStageCondition(&v1alpha1.StageCondition{Tags: []string{"v[1"}}))
+}
+
+func TestIsRef(t *testing.T) {
+	for _, ref := range []string{
+		"master",
+		"refs/heads/feature/a-b_c",
+		"refs/pull/12/head",
+		"refs/heads/$(id)",
+		"0123456789abcdef",
+	} {
+		assert.Empty(t, isRef(ref), ref)
+	}
+	for _, ref := range []string{
+		"@",
+		"-master",
+		"/master",
+		"refs/heads/",
+		"refs/heads/a.",
+		"refs//heads",
+		"refs/heads/a..b",
+		"refs/heads/a@{1}",
+		"refs/heads/a b",
+		"refs/heads/a~1",
+		"refs/heads/a^",
+		"refs/heads/a:b",
+		"refs/heads/a?",
+		"refs/heads/a*",
+		"refs/heads/a[",
+		"refs/heads/a\\b",
+		"refs/heads/a\nb",
+		"refs/heads/.a",
+		"refs/heads/a.lock",
+	} {
+		assert.NotEmpty(t, isRef(ref), ref)
+	}
+}
@@ -39,6 +39,7 @@ type EventsGetter interface {
 type EventInterface interface {
 	Create(*v1alpha1.Event) (*v1alpha1.Event, error)
 	Update(*v1alpha1.Event) (*v1alpha1.Event, error)
+	UpdateStatus(*v1alpha1.Event) (*v1alpha1.Event, error)
 	Delete(name string, options *v1.DeleteOptions) error
 	DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error
 	Get(name string, options v1.GetOptions) (*v1alpha1.Event, error)
@@ -132,6 +133,22 @@ func (c *events) Update(event *v1alpha1.Event) (result *v1alpha1.Event, err erro
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+
+func (c *events) UpdateStatus(event *v1alpha1.Event) (result *v1alpha1.Event, err error) {
+	result = &v1alpha1.Event{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("events").
+		Name(event.Name).
+		SubResource("status").
+		Body(event).
+		Do().
+		Into(result)
+	return
+}
+
 // Delete takes name of the event and deletes it. Returns an error if one occurs.
 func (c *events) Delete(name string, options *v1.DeleteOptions) error {
 	return c.client.Delete().
@@ -28,6 +28,10 @@ type FakeMarioV1alpha1 struct {
 	*testing.Fake
 }
 
+func (c *FakeMarioV1alpha1) Actions(namespace string) v1alpha1.ActionInterface {
+	return &FakeActions{c, namespace}
+}
+
 func (c *FakeMarioV1alpha1) Events(namespace string) v1alpha1.EventInterface {
 	return &FakeEvents{c, namespace}
 }
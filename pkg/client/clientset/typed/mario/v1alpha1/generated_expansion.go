@@ -18,6 +18,8 @@ limitations under the License.
 
 package v1alpha1
 
+type ActionExpansion interface{}
+
 type EventExpansion interface{}
 
 type FlowExpansion interface{}
@@ -26,6 +26,7 @@ import (
 
 type MarioV1alpha1Interface interface {
 	RESTClient() rest.Interface
+	ActionsGetter
 	EventsGetter
 	FlowsGetter
 	MariosGetter
@@ -37,6 +38,10 @@ type MarioV1alpha1Client struct {
 	restClient rest.Interface
 }
 
+func (c *MarioV1alpha1Client) Actions(namespace string) ActionInterface {
+	return newActions(c, namespace)
+}
+
 func (c *MarioV1alpha1Client) Events(namespace string) EventInterface {
 	return newEvents(c, namespace)
 }
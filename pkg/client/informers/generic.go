@@ -53,6 +53,8 @@ func (f *genericInformer) Lister() cache.GenericLister {
 func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
 	switch resource {
 	// Group=mario.oooops.com, Version=v1alpha1
+	case v1alpha1.SchemeGroupVersion.WithResource("actions"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Mario().V1alpha1().Actions().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("events"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Mario().V1alpha1().Events().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("flows"):
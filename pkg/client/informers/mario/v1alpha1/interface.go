@@ -24,6 +24,8 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// Actions returns a ActionInformer.
+	Actions() ActionInformer
 	// Events returns a EventInformer.
 	Events() EventInformer
 	// Flows returns a FlowInformer.
@@ -45,6 +47,11 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// Actions returns a ActionInformer.
+func (v *version) Actions() ActionInformer {
+	return &actionInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // Events returns a EventInformer.
 func (v *version) Events() EventInformer {
 	return &eventInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
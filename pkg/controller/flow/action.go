@@ -0,0 +1,54 @@
+package flow
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	"github.com/liubog2008/oooops/pkg/mario/template"
+)
+
+// checkStageAction returns stageError if template of stage action
+// can't be resolved or env of action is invalid
+func (c *Controller) checkStageAction(flow *v1alpha1.Flow, stage *v1alpha1.Stage) error {
+	action := template.FindAction(flow.Spec.Mario, stage.Action)
+	if action == nil {
+		return nil
+	}
+
+	// lister only returns not found error, so all errors are permanent
+	tmpl, err := c.resolveActionTemplate(flow.Namespace, flow.Spec.Mario, action)
+	if err != nil {
+		return &stageError{
+			reason:  v1alpha1.StageReasonActionInvalid,
+			message: fmt.Sprintf("action %s of stage %s is invalid: %v", action.Name, stage.Name, err),
+		}
+	}
+
+	for _, leg := range expandMatrix(stage) {
+		if _, err := template.Env(action, tmpl, flow.Spec.Git.Ref, leg.params); err != nil {
+			return &stageError{
+				reason:  v1alpha1.StageReasonActionInvalid,
+				message: fmt.Sprintf("env of action %s of stage %s is invalid: %v", action.Name, stage.Name, err),
+			}
+		}
+	}
+
+	return nil
+}
+
+// resolveActionTemplate returns template of the action,
+// if action is imported, template will be rendered by args of action
+func (c *Controller) resolveActionTemplate(namespace string, mario *v1alpha1.Mario, action *v1alpha1.MarioAction) (*v1alpha1.ActionTemplate, error) {
+	return template.Resolve(namespace, mario, action, func(ns, name string) (*v1alpha1.Action, error) {
+		imported, err := c.actionLister.Actions(ns).Get(name)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return nil, fmt.Errorf("imported action %s/%s is not found", ns, name)
+			}
+			return nil, err
+		}
+		return imported, nil
+	})
+}
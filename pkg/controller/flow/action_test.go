@@ -0,0 +1,82 @@
+package flow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	mariolisters "github.com/liubog2008/oooops/pkg/client/listers/mario/v1alpha1"
+)
+
+func TestCheckStageAction(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		cache.NamespaceIndex: cache.MetaNamespaceIndexFunc,
+	})
+	assert.NoError(t, indexer.Add(&v1alpha1.Action{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "build",
+		},
+		Spec: v1alpha1.ActionSpec{
+			Template: &v1alpha1.ActionTemplate{
+				Image: "golang",
+				Version: v1alpha1.VersionDefinition{
+					EnvName: "VERSION",
+				},
+			},
+		},
+	}))
+
+	c := &Controller{
+		actionLister: mariolisters.NewActionLister(indexer),
+	}
+
+	newFlow := func(imports []string, env ...v1alpha1.ActionEnvVar) *v1alpha1.Flow {
+		return &v1alpha1.Flow{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      "test",
+			},
+			Spec: v1alpha1.FlowSpec{
+				Mario: &v1alpha1.Mario{
+					Spec: v1alpha1.MarioSpec{
+						Imports: imports,
+						Actions: []v1alpha1.MarioAction{
+							{Name: "build", Env: env},
+						},
+					},
+				},
+			},
+		}
+	}
+	stage := &v1alpha1.Stage{Name: "build", Action: "build"}
+
+	assert.NoError(t, c.checkStageAction(newFlow([]string{"build"}), stage))
+
+	// imported action doesn't exist
+	err := c.checkStageAction(newFlow(nil), stage)
+	assert.IsType(t, &stageError{}, err)
+	assert.Equal(t, v1alpha1.StageReasonActionInvalid, err.(*stageError).reason)
+
+	err = c.checkStage(&v1alpha1.Flow{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test"},
+		Spec: v1alpha1.FlowSpec{
+			Mario: &v1alpha1.Mario{
+				Spec: v1alpha1.MarioSpec{
+					Imports: []string{"deploy"},
+					Actions: []v1alpha1.MarioAction{{Name: "deploy"}},
+				},
+			},
+		},
+	}, &v1alpha1.Stage{Name: "deploy", Action: "deploy"})
+	assert.IsType(t, &stageError{}, err)
+	assert.Equal(t, v1alpha1.StageReasonActionInvalid, err.(*stageError).reason)
+
+	// env is conflict with version env
+	err = c.checkStageAction(newFlow([]string{"build"}, v1alpha1.ActionEnvVar{Name: "VERSION", Value: "1"}), stage)
+	assert.IsType(t, &stageError{}, err)
+	assert.Equal(t, v1alpha1.StageReasonActionInvalid, err.(*stageError).reason)
+}
@@ -18,6 +18,7 @@ import (
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog"
 
+	"github.com/liubog2008/oooops/pkg/apis/mario/defaults"
 	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
 	"github.com/liubog2008/oooops/pkg/client/clientset"
 	"github.com/liubog2008/oooops/pkg/client/clientset/scheme"
@@ -42,11 +43,24 @@ type ControllerOptions struct {
 
 	FlowInformer marioinformers.FlowInformer
 
+	ActionInformer marioinformers.ActionInformer
+
 	PVCInformer coreinformers.PersistentVolumeClaimInformer
 
 	ConfigMapInformer coreinformers.ConfigMapInformer
 
+	// SecretInformer only needs to watch mario token secrets,
+	// which are labeled by DefaultFlowTokenLabelKey
+	SecretInformer coreinformers.SecretInformer
+
 	PodInformer coreinformers.PodInformer
+
+	// Defaulter sets defaults of flows before they are synced
+	Defaulter defaults.Interface
+
+	// PodPolicies defines pod policies of namespaces,
+	// default policy is used for all namespaces if it is nil
+	PodPolicies *PodPolicies
 }
 
 // Controller defines controller to manage flow lifecycle and generate jobs
@@ -59,11 +73,13 @@ type Controller struct {
 	kubeClient kubernetes.Interface
 	extClient  clientset.Interface
 
-	flowLister mariolisters.FlowLister
-	jobLister  batchlisters.JobLister
-	pvcLister  corelisters.PersistentVolumeClaimLister
-	cmLister   corelisters.ConfigMapLister
-	podLister  corelisters.PodLister
+	flowLister   mariolisters.FlowLister
+	actionLister mariolisters.ActionLister
+	jobLister    batchlisters.JobLister
+	pvcLister    corelisters.PersistentVolumeClaimLister
+	cmLister     corelisters.ConfigMapLister
+	secretLister corelisters.SecretLister
+	podLister    corelisters.PodLister
 
 	informersSynced []cache.InformerSynced
 
@@ -74,6 +90,10 @@ type Controller struct {
 
 	buildReconciler controller.ReconcilerBuilder
 
+	defaulter defaults.Interface
+
+	podPolicies *PodPolicies
+
 	marioImage string
 	gitImage   string
 }
@@ -93,25 +113,33 @@ func NewController(opt *ControllerOptions) *Controller {
 
 		informersSynced: []cache.InformerSynced{
 			opt.FlowInformer.Informer().HasSynced,
+			opt.ActionInformer.Informer().HasSynced,
 			opt.JobInformer.Informer().HasSynced,
 			opt.PVCInformer.Informer().HasSynced,
 			opt.ConfigMapInformer.Informer().HasSynced,
+			opt.SecretInformer.Informer().HasSynced,
 			opt.PodInformer.Informer().HasSynced,
 		},
 
 		queue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "flow"),
 
-		flowLister: opt.FlowInformer.Lister(),
-		jobLister:  opt.JobInformer.Lister(),
-		pvcLister:  opt.PVCInformer.Lister(),
-		cmLister:   opt.ConfigMapInformer.Lister(),
-		podLister:  opt.PodInformer.Lister(),
+		flowLister:   opt.FlowInformer.Lister(),
+		actionLister: opt.ActionInformer.Lister(),
+		jobLister:    opt.JobInformer.Lister(),
+		pvcLister:    opt.PVCInformer.Lister(),
+		cmLister:     opt.ConfigMapInformer.Lister(),
+		secretLister: opt.SecretInformer.Lister(),
+		podLister:    opt.PodInformer.Lister(),
 
 		eventBroadcaster: broadcaster,
 		eventRecorder:    recorder,
 
 		buildReconciler: controller.BuildRateLimitingReconciler,
 
+		defaulter: opt.Defaulter,
+
+		podPolicies: opt.PodPolicies,
+
 		gitImage:   "alpine/git:v2.24.3",
 		marioImage: "registry.cn-hangzhou.aliyuncs.com/liubog2008/oooops-mario:v0.0.0-1098046dd20868-dirty",
 	}
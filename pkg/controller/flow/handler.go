@@ -92,11 +92,17 @@ func (c *Controller) addFlow(obj interface{}) {
 }
 
 func (c *Controller) updateFlow(old, cur interface{}) {
+	oldFlow, ok1 := old.(*v1alpha1.Flow)
+	curFlow, ok2 := cur.(*v1alpha1.Flow)
+	if ok1 && ok2 && !isFlowFinished(oldFlow) && isFlowFinished(curFlow) {
+		c.enqueueConcurrentFlows(curFlow)
+	}
 	c.addFlow(cur)
 }
 
 func (c *Controller) deleteFlow(obj interface{}) {
 	if flow, ok := obj.(*v1alpha1.Flow); ok {
+		c.enqueueConcurrentFlows(flow)
 		c.addFlow(flow)
 		return
 	}
@@ -111,6 +117,7 @@ func (c *Controller) deleteFlow(obj interface{}) {
 		utilruntime.HandleError(fmt.Errorf("tombstone contained object that is not a Flow: %#v", tombstone.Obj))
 		return
 	}
+	c.enqueueConcurrentFlows(flow)
 	c.addFlow(flow)
 }
 
@@ -9,6 +9,7 @@ import (
 	"k8s.io/klog"
 
 	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	"github.com/liubog2008/oooops/pkg/mario/stages"
 )
 
 func (c *Controller) getFlowFromRef(ns string, ref *metav1.OwnerReference) *v1alpha1.Flow {
@@ -70,6 +71,29 @@ func NewFlowCondition(t v1alpha1.FlowConditionType, status corev1.ConditionStatu
 	}
 }
 
+// getFlowCondition returns condition of flow with type t
+func getFlowCondition(status *v1alpha1.FlowStatus, t v1alpha1.FlowConditionType) *v1alpha1.FlowCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == t {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// setFlowCondition sets condition in status,
+// transition time is kept if status of condition is not changed
+func setFlowCondition(status *v1alpha1.FlowStatus, cond *v1alpha1.FlowCondition) {
+	if c := getFlowCondition(status, cond.Type); c != nil {
+		if c.Status == cond.Status {
+			cond.LastTransitionTime = c.LastTransitionTime
+		}
+		*c = *cond
+		return
+	}
+	status.Conditions = append(status.Conditions, *cond)
+}
+
 func IsJobComplete(job *batchv1.Job) bool {
 	s, ok := getJobCondition(job, batchv1.JobComplete)
 	if !ok {
@@ -126,3 +150,31 @@ func (c *Controller) cleanInvalidJob(message, namespace, name string) {
 func nameJoin(parts ...string) string {
 	return strings.Join(parts, "-")
 }
+
+// stageError defines why job of stage can't be created
+type stageError struct {
+	reason  string
+	message string
+}
+
+// Error implements error interface
+func (e *stageError) Error() string {
+	return e.message
+}
+
+// checkStage returns stageError if job of stage can't be created
+func (c *Controller) checkStage(flow *v1alpha1.Flow, stage *v1alpha1.Stage) error {
+	if err := c.checkStageAction(flow, stage); err != nil {
+		return err
+	}
+	if err := c.checkStageSecrets(flow, stage); err != nil {
+		return err
+	}
+	return c.checkStagePolicy(flow, stage)
+}
+
+// isStageSkipped returns true if conditions of stage are not matched
+// by the event which triggers the flow
+func isStageSkipped(flow *v1alpha1.Flow, stage *v1alpha1.Stage) bool {
+	return !stages.MatchCondition(stage.When, flow.Spec.Git.Ref, flow.Spec.Extra)
+}
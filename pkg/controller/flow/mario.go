@@ -6,11 +6,13 @@ import (
 	"net/http"
 
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog"
 
 	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
-	"github.com/liubog2008/oooops/pkg/client/clientset/scheme"
+	"github.com/liubog2008/oooops/pkg/apis/mario/validation"
+	"github.com/liubog2008/oooops/pkg/mario/template"
 )
 
 func (c *Controller) attachMario(flow *v1alpha1.Flow, jobMap map[string]*batchv1.Job) (bool, error) {
@@ -18,6 +20,10 @@ func (c *Controller) attachMario(flow *v1alpha1.Flow, jobMap map[string]*batchv1
 	if flow.Spec.Mario != nil {
 		return false, nil
 	}
+	// if mario is invalid, don't fetch it again
+	if isMarioInvalid(flow) {
+		return false, nil
+	}
 	// if mario job is missing, can't attach mario
 	marioJob, ok := jobMap[v1alpha1.FlowStageMario]
 	if !ok {
@@ -31,10 +37,14 @@ func (c *Controller) attachMario(flow *v1alpha1.Flow, jobMap map[string]*batchv1
 	if err != nil {
 		return false, err
 	}
+	token, err := c.getMarioToken(flow)
+	if err != nil {
+		return false, err
+	}
 	var mario *v1alpha1.Mario
 	for _, pod := range pods {
 		if IsPodReady(pod) && metav1.IsControlledBy(pod, marioJob) {
-			m, err := c.fetchMario(pod.Status.PodIP)
+			m, err := c.fetchMario(pod.Status.PodIP, token)
 			if err != nil {
 				klog.Warningf("can't fetch mario from %s: %s", pod.Status.PodIP, err)
 				continue
@@ -46,6 +56,19 @@ func (c *Controller) attachMario(flow *v1alpha1.Flow, jobMap map[string]*batchv1
 		// TODO(liubog2008): do something to recover
 		return false, nil
 	}
+	// invalid mario can't be attached because flow will be rejected by admission webhook
+	if errs := validation.ValidateMario(mario); len(errs) != 0 {
+		c.eventRecorder.Eventf(flow, corev1.EventTypeWarning, v1alpha1.FlowReasonInvalidMario, "Mario is invalid: %v", errs.ToAggregate())
+		if err := c.failFlow(flow, NewFlowCondition(
+			v1alpha1.FlowMarioReady,
+			corev1.ConditionFalse,
+			v1alpha1.FlowReasonInvalidMario,
+			errs.ToAggregate().Error(),
+		)); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
 	flow.Spec.Mario = mario
 	if _, err := c.extClient.MarioV1alpha1().Flows(flow.Namespace).Update(flow); err != nil {
 		return false, err
@@ -54,13 +77,19 @@ func (c *Controller) attachMario(flow *v1alpha1.Flow, jobMap map[string]*batchv1
 	return true, nil
 }
 
-func (c *Controller) fetchMario(ip string) (*v1alpha1.Mario, error) {
+// isMarioInvalid returns true if mario fetched from repo is invalid
+func isMarioInvalid(flow *v1alpha1.Flow) bool {
+	cond := getFlowCondition(&flow.Status, v1alpha1.FlowMarioReady)
+	return cond != nil && cond.Reason == v1alpha1.FlowReasonInvalidMario
+}
+
+func (c *Controller) fetchMario(ip, token string) (*v1alpha1.Mario, error) {
 	req, err := http.NewRequest("GET", "http://"+ip+":8080", nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("ContentType", "application/json")
-	req.Header.Set("Authorization", "Bearer test")
+	req.Header.Set("Authorization", "Bearer "+token)
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
@@ -72,14 +101,11 @@ func (c *Controller) fetchMario(ip string) (*v1alpha1.Mario, error) {
 		return nil, err
 	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("can't fetch mario [%s]: %s", resp.StatusCode, body)
+		return nil, fmt.Errorf("can't fetch mario [%d]: %s", resp.StatusCode, body)
 	}
 
 	m := v1alpha1.Mario{}
-
-	decoder := scheme.Codecs.UniversalDecoder(v1alpha1.SchemeGroupVersion)
-
-	if _, _, err := decoder.Decode(body, nil, &m); err != nil {
+	if err := template.Decode(body, &m); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,83 @@
+package flow
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	"github.com/liubog2008/oooops/pkg/apis/mario/validation"
+	"github.com/liubog2008/oooops/pkg/mario/template"
+)
+
+// stageLeg defines a job of stage, stage without matrix has only one leg
+type stageLeg struct {
+	// suffix will be appended to name of stage job,
+	// it is empty if stage has no matrix
+	suffix string
+	// params defines parameters of leg in order of matrix
+	params []corev1.EnvVar
+}
+
+// key returns key of leg job in job map
+func (l *stageLeg) key(stage *v1alpha1.Stage) string {
+	if l.suffix == "" {
+		return v1alpha1.UserJobPrefix + stage.Name
+	}
+	return v1alpha1.UserJobPrefix + nameJoin(stage.Name, l.suffix)
+}
+
+// jobName returns name of leg job
+func (l *stageLeg) jobName(flow *v1alpha1.Flow, stage *v1alpha1.Stage) string {
+	return validation.StageJobName(flow.Name, stage.Name, l.suffix)
+}
+
+// parameters returns parameters of leg as a map
+func (l *stageLeg) parameters() map[string]string {
+	m := make(map[string]string, len(l.params))
+	for _, p := range l.params {
+		m[p.Name] = p.Value
+	}
+	return m
+}
+
+// expandMatrix returns all legs of stage in order of matrix
+func expandMatrix(stage *v1alpha1.Stage) []stageLeg {
+	if len(stage.Matrix) == 0 {
+		return []stageLeg{{}}
+	}
+
+	combinations := template.ExpandMatrix(stage.Matrix)
+
+	legs := make([]stageLeg, 0, len(combinations))
+	for _, params := range combinations {
+		legs = append(legs, stageLeg{
+			suffix: validation.LegSuffix(params),
+			params: params,
+		})
+	}
+	return legs
+}
+
+// stagePhase returns phase of stage from phases of its legs
+func stagePhase(phases []string) string {
+	missing, completed := 0, 0
+	for _, phase := range phases {
+		switch phase {
+		case v1alpha1.StageJobTimedOut:
+			return v1alpha1.StageJobTimedOut
+		case v1alpha1.StageJobFailed:
+			return v1alpha1.StageJobFailed
+		case v1alpha1.StageJobMissing:
+			missing++
+		case v1alpha1.StageJobComplete:
+			completed++
+		}
+	}
+
+	if missing == len(phases) {
+		return v1alpha1.StageJobMissing
+	}
+	if completed == len(phases) {
+		return v1alpha1.StageJobComplete
+	}
+	return v1alpha1.StageJobRunning
+}
@@ -0,0 +1,54 @@
+package flow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	"github.com/liubog2008/oooops/pkg/apis/mario/validation"
+)
+
+func TestExpandMatrix(t *testing.T) {
+	stage := &v1alpha1.Stage{
+		Name: "test",
+		Matrix: []v1alpha1.MatrixParameter{
+			{Name: "GOVERSION", Values: []string{"1.13", "1.14"}},
+			{Name: "GOARCH", Values: []string{"amd64", "arm64"}},
+		},
+	}
+
+	legs := expandMatrix(stage)
+	assert.Len(t, legs, 4)
+	assert.Equal(t, map[string]string{"GOVERSION": "1.13", "GOARCH": "amd64"}, legs[0].parameters())
+	assert.Equal(t, map[string]string{"GOVERSION": "1.14", "GOARCH": "arm64"}, legs[3].parameters())
+
+	suffixes := map[string]struct{}{}
+	for _, leg := range legs {
+		assert.Len(t, leg.suffix, validation.LegSuffixLength)
+		suffixes[leg.suffix] = struct{}{}
+	}
+	assert.Len(t, suffixes, 4)
+
+	// suffix is stable if order of parameters is changed
+	reordered := &v1alpha1.Stage{
+		Name: "test",
+		Matrix: []v1alpha1.MatrixParameter{
+			{Name: "GOARCH", Values: []string{"amd64"}},
+			{Name: "GOVERSION", Values: []string{"1.13"}},
+		},
+	}
+	assert.Equal(t, legs[0].suffix, expandMatrix(reordered)[0].suffix)
+	assert.Equal(t, "user-test-"+legs[0].suffix, legs[0].key(stage))
+
+	single := expandMatrix(&v1alpha1.Stage{Name: "build"})
+	assert.Len(t, single, 1)
+	assert.Equal(t, "user-build", single[0].key(&v1alpha1.Stage{Name: "build"}))
+}
+
+func TestStagePhase(t *testing.T) {
+	assert.Equal(t, v1alpha1.StageJobMissing, stagePhase([]string{v1alpha1.StageJobMissing, v1alpha1.StageJobMissing}))
+	assert.Equal(t, v1alpha1.StageJobRunning, stagePhase([]string{v1alpha1.StageJobComplete, v1alpha1.StageJobMissing}))
+	assert.Equal(t, v1alpha1.StageJobComplete, stagePhase([]string{v1alpha1.StageJobComplete, v1alpha1.StageJobComplete}))
+	assert.Equal(t, v1alpha1.StageJobFailed, stagePhase([]string{v1alpha1.StageJobComplete, v1alpha1.StageJobFailed}))
+}
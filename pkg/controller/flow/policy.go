@@ -0,0 +1,161 @@
+package flow
+
+import (
+	"fmt"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	"github.com/liubog2008/oooops/pkg/mario/template"
+)
+
+// PodPolicies defines pod policies of namespaces, it is configured by operator
+// so that it can't be changed by users of the restricted namespaces
+type PodPolicies struct {
+	// Default defines policy of namespaces which are not listed
+	Default PodPolicy `json:"default,omitempty"`
+	// Namespaces defines policies by namespace
+	Namespaces map[string]PodPolicy `json:"namespaces,omitempty"`
+}
+
+// LoadPodPolicies loads pod policies from yaml or json file
+func LoadPodPolicies(file string) (*PodPolicies, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	policies := PodPolicies{}
+	if err := yaml.NewYAMLOrJSONDecoder(f, 4096).Decode(&policies); err != nil {
+		return nil, fmt.Errorf("can't decode pod policies from %s: %v", file, err)
+	}
+	return &policies, nil
+}
+
+// PodPolicy defines which pod options of actions are allowed in a namespace.
+// If policy is not defined, only default service account can be used and
+// privileged container is not allowed
+type PodPolicy struct {
+	// AllowedServiceAccounts defines names of service accounts
+	// which can be used by action pods
+	AllowedServiceAccounts []string `json:"allowedServiceAccounts,omitempty"`
+	// AllowPrivileged means privileged container is allowed
+	AllowPrivileged bool `json:"allowPrivileged,omitempty"`
+	// MaxResources defines max limits and requests of action container
+	MaxResources corev1.ResourceList `json:"maxResources,omitempty"`
+}
+
+// getPodPolicy returns pod policy of namespace
+func (c *Controller) getPodPolicy(namespace string) *PodPolicy {
+	if c.podPolicies == nil {
+		return &PodPolicy{}
+	}
+	if policy, ok := c.podPolicies.Namespaces[namespace]; ok {
+		return &policy
+	}
+	return &c.podPolicies.Default
+}
+
+// validate returns error if pod options violate the policy
+func (p *PodPolicy) validate(opts *v1alpha1.PodOptions) error {
+	if opts.ServiceAccountName != "" && opts.ServiceAccountName != "default" {
+		allowed := false
+		for _, name := range p.AllowedServiceAccounts {
+			if name == opts.ServiceAccountName {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("service account %s is not allowed", opts.ServiceAccountName)
+		}
+	}
+
+	if sc := opts.SecurityContext; sc != nil && !p.AllowPrivileged {
+		if sc.Privileged != nil && *sc.Privileged {
+			return fmt.Errorf("privileged container is not allowed")
+		}
+		if sc.AllowPrivilegeEscalation != nil && *sc.AllowPrivilegeEscalation {
+			return fmt.Errorf("privilege escalation is not allowed")
+		}
+		if sc.Capabilities != nil && len(sc.Capabilities.Add) != 0 {
+			return fmt.Errorf("adding capabilities is not allowed")
+		}
+	}
+
+	if opts.Resources != nil {
+		for _, list := range []corev1.ResourceList{opts.Resources.Limits, opts.Resources.Requests} {
+			for name, quantity := range list {
+				max, ok := p.MaxResources[name]
+				if !ok {
+					continue
+				}
+				if quantity.Cmp(max) > 0 {
+					return fmt.Errorf("resource %s %s exceeds max %s", name, quantity.String(), max.String())
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// mergePodOptions merges pod options, non-empty field of latter options
+// overrides the former one
+func mergePodOptions(options ...*v1alpha1.PodOptions) *v1alpha1.PodOptions {
+	merged := v1alpha1.PodOptions{}
+	for _, opts := range options {
+		if opts == nil {
+			continue
+		}
+		if opts.ServiceAccountName != "" {
+			merged.ServiceAccountName = opts.ServiceAccountName
+		}
+		if opts.Resources != nil {
+			merged.Resources = opts.Resources
+		}
+		if len(opts.NodeSelector) != 0 {
+			merged.NodeSelector = opts.NodeSelector
+		}
+		if len(opts.Tolerations) != 0 {
+			merged.Tolerations = opts.Tolerations
+		}
+		if opts.Affinity != nil {
+			merged.Affinity = opts.Affinity
+		}
+		if opts.SecurityContext != nil {
+			merged.SecurityContext = opts.SecurityContext
+		}
+	}
+	return merged.DeepCopy()
+}
+
+// checkStagePolicy returns stageError if pod options of stage violate
+// pod policy of namespace
+func (c *Controller) checkStagePolicy(flow *v1alpha1.Flow, stage *v1alpha1.Stage) error {
+	action := template.FindAction(flow.Spec.Mario, stage.Action)
+	if action == nil {
+		return nil
+	}
+
+	tmpl, err := c.resolveActionTemplate(flow.Namespace, flow.Spec.Mario, action)
+	if err != nil {
+		// error of template is reported by checkStageAction
+		return nil
+	}
+
+	policy := c.getPodPolicy(flow.Namespace)
+
+	opts := mergePodOptions(&tmpl.PodOptions, &action.PodOptions, &stage.PodOptions)
+	if err := policy.validate(opts); err != nil {
+		return &stageError{
+			reason:  v1alpha1.StageReasonPolicyViolated,
+			message: fmt.Sprintf("pod options of stage %s violate pod policy: %v", stage.Name, err),
+		}
+	}
+
+	return nil
+}
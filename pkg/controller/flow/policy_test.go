@@ -0,0 +1,130 @@
+package flow
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+func TestMergePodOptions(t *testing.T) {
+	tmpl := &v1alpha1.PodOptions{
+		ServiceAccountName: "builder",
+		NodeSelector:       map[string]string{"size": "small"},
+	}
+	action := &v1alpha1.PodOptions{
+		ServiceAccountName: "deployer",
+	}
+	stage := &v1alpha1.PodOptions{
+		NodeSelector: map[string]string{"size": "large"},
+	}
+
+	merged := mergePodOptions(tmpl, action, stage)
+	assert.Equal(t, "deployer", merged.ServiceAccountName)
+	assert.Equal(t, map[string]string{"size": "large"}, merged.NodeSelector)
+	assert.Nil(t, merged.Affinity)
+}
+
+func TestPodPolicyValidate(t *testing.T) {
+	privileged := true
+	policy := &PodPolicy{
+		AllowedServiceAccounts: []string{"deployer"},
+		MaxResources: corev1.ResourceList{
+			corev1.ResourceCPU: resource.MustParse("4"),
+		},
+	}
+
+	cases := []struct {
+		desc  string
+		opts  v1alpha1.PodOptions
+		valid bool
+	}{
+		{
+			desc:  "empty options",
+			valid: true,
+		},
+		{
+			desc:  "allowed service account",
+			opts:  v1alpha1.PodOptions{ServiceAccountName: "deployer"},
+			valid: true,
+		},
+		{
+			desc: "unknown service account",
+			opts: v1alpha1.PodOptions{ServiceAccountName: "admin"},
+		},
+		{
+			desc: "privileged container",
+			opts: v1alpha1.PodOptions{
+				SecurityContext: &corev1.SecurityContext{Privileged: &privileged},
+			},
+		},
+		{
+			desc: "resources in range",
+			opts: v1alpha1.PodOptions{
+				Resources: &corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("2"),
+						corev1.ResourceMemory: resource.MustParse("8Gi"),
+					},
+				},
+			},
+			valid: true,
+		},
+		{
+			desc: "resources exceed max",
+			opts: v1alpha1.PodOptions{
+				Resources: &corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{
+						corev1.ResourceCPU: resource.MustParse("8"),
+					},
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		err := policy.validate(&c.opts)
+		if c.valid {
+			assert.NoError(t, err, c.desc)
+		} else {
+			assert.Error(t, err, c.desc)
+		}
+	}
+}
+
+func TestGetPodPolicy(t *testing.T) {
+	f, err := ioutil.TempFile("", "pod-policy")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(`default:
+  allowedServiceAccounts: [builder]
+namespaces:
+  ci:
+    allowedServiceAccounts: [deployer]
+    allowPrivileged: true
+`)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	policies, err := LoadPodPolicies(f.Name())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	c := &Controller{podPolicies: policies}
+	assert.Equal(t, []string{"deployer"}, c.getPodPolicy("ci").AllowedServiceAccounts)
+	assert.True(t, c.getPodPolicy("ci").AllowPrivileged)
+	assert.Equal(t, []string{"builder"}, c.getPodPolicy("other").AllowedServiceAccounts)
+
+	// only the default service account is allowed without policies
+	c = &Controller{}
+	assert.Error(t, c.getPodPolicy("ci").validate(&v1alpha1.PodOptions{ServiceAccountName: "deployer"}))
+}
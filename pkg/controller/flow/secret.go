@@ -0,0 +1,88 @@
+package flow
+
+import (
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	"github.com/liubog2008/oooops/pkg/mario/template"
+)
+
+const (
+	secretVolumePrefix = "secret"
+)
+
+// checkStageSecrets returns stageError if some secrets of stage action
+// are not allowed by pipe or not found
+func (c *Controller) checkStageSecrets(flow *v1alpha1.Flow, stage *v1alpha1.Stage) error {
+	action := template.FindAction(flow.Spec.Mario, stage.Action)
+	if action == nil {
+		return nil
+	}
+
+	allowed := map[string]struct{}{}
+	for _, name := range flow.Spec.AllowedSecrets {
+		allowed[name] = struct{}{}
+	}
+
+	for i := range action.Secrets {
+		s := &action.Secrets[i]
+		if _, ok := allowed[s.Name]; !ok {
+			return &stageError{
+				reason:  v1alpha1.StageReasonSecretNotAllowed,
+				message: fmt.Sprintf("secret %s of action %s is not allowed by pipe", s.Name, action.Name),
+			}
+		}
+
+		// secrets are not cached, only existence of them is checked
+		if _, err := c.kubeClient.CoreV1().Secrets(flow.Namespace).Get(s.Name, metav1.GetOptions{}); err != nil {
+			if errors.IsNotFound(err) {
+				return &stageError{
+					reason:  v1alpha1.StageReasonSecretNotFound,
+					message: fmt.Sprintf("secret %s of action %s is not found", s.Name, action.Name),
+				}
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// secretVolumeName returns name of volume of the ith secret
+func secretVolumeName(i int) string {
+	return nameJoin(secretVolumePrefix, strconv.Itoa(i))
+}
+
+// secretVolumes returns volumes of action secrets
+func secretVolumes(action *v1alpha1.MarioAction) []corev1.Volume {
+	volumes := make([]corev1.Volume, 0, len(action.Secrets))
+	for i := range action.Secrets {
+		volumes = append(volumes, corev1.Volume{
+			Name: secretVolumeName(i),
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: action.Secrets[i].Name,
+				},
+			},
+		})
+	}
+	return volumes
+}
+
+// secretVolumeMounts returns volume mounts of action secrets
+func secretVolumeMounts(action *v1alpha1.MarioAction) []corev1.VolumeMount {
+	mounts := make([]corev1.VolumeMount, 0, len(action.Secrets))
+	for i := range action.Secrets {
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      secretVolumeName(i),
+			MountPath: action.Secrets[i].MountPath,
+			ReadOnly:  true,
+		})
+	}
+	return mounts
+}
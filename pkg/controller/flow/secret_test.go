@@ -0,0 +1,59 @@
+package flow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+func TestCheckStageSecrets(t *testing.T) {
+	c := &Controller{
+		kubeClient: kubefake.NewSimpleClientset(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      "registry",
+			},
+		}),
+	}
+
+	newFlow := func(allowed []string, secrets ...string) *v1alpha1.Flow {
+		action := v1alpha1.MarioAction{Name: "push"}
+		for _, s := range secrets {
+			action.Secrets = append(action.Secrets, v1alpha1.ActionSecret{
+				Name:      s,
+				MountPath: "/secrets/" + s,
+			})
+		}
+		return &v1alpha1.Flow{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      "test",
+			},
+			Spec: v1alpha1.FlowSpec{
+				Mario: &v1alpha1.Mario{
+					Spec: v1alpha1.MarioSpec{
+						Actions: []v1alpha1.MarioAction{action},
+					},
+				},
+				AllowedSecrets: allowed,
+			},
+		}
+	}
+	stage := &v1alpha1.Stage{Name: "push", Action: "push"}
+
+	assert.NoError(t, c.checkStageSecrets(newFlow(nil), stage))
+	assert.NoError(t, c.checkStageSecrets(newFlow([]string{"registry"}, "registry"), stage))
+
+	err := c.checkStageSecrets(newFlow(nil, "registry"), stage)
+	assert.IsType(t, &stageError{}, err)
+	assert.Equal(t, v1alpha1.StageReasonSecretNotAllowed, err.(*stageError).reason)
+
+	err = c.checkStageSecrets(newFlow([]string{"deploy-key"}, "deploy-key"), stage)
+	assert.IsType(t, &stageError{}, err)
+	assert.Equal(t, v1alpha1.StageReasonSecretNotFound, err.(*stageError).reason)
+}
@@ -6,12 +6,19 @@ import (
 	"time"
 
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog"
 
 	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	"github.com/liubog2008/oooops/pkg/apis/mario/validation"
+)
+
+const (
+	// flowReasonInvalid is reason of event when flow can't pass validation
+	flowReasonInvalid = "Invalid"
 )
 
 func (c *Controller) syncFlow(key string) error {
@@ -26,13 +33,26 @@ func (c *Controller) syncFlow(key string) error {
 		return err
 	}
 
-	flow, err := c.flowLister.Flows(ns).Get(name)
+	cached, err := c.flowLister.Flows(ns).Get(name)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			return nil
 		}
 		return err
 	}
+	flow := cached.DeepCopy()
+	c.defaulter.DefaultFlow(flow)
+
+	// invalid flow is failed and will be synced again after it is updated
+	if errs := validation.ValidateFlow(flow); len(errs) != 0 {
+		c.eventRecorder.Eventf(flow, corev1.EventTypeWarning, flowReasonInvalid, "Flow is invalid: %v", errs.ToAggregate())
+		return c.failFlow(flow, NewFlowCondition(
+			v1alpha1.FlowInvalid,
+			corev1.ConditionTrue,
+			v1alpha1.FlowReasonInvalidSpec,
+			errs.ToAggregate().Error(),
+		))
+	}
 
 	selector, err := metav1.LabelSelectorAsSelector(flow.Spec.Selector)
 	if err != nil {
@@ -48,13 +68,47 @@ func (c *Controller) syncFlow(key string) error {
 
 	jobMap := c.calculateJobMap(flow, jobs)
 
-	attached, err := c.attachMario(flow, jobMap)
-	if err != nil {
+	timedOut := isFlowTimedOut(flow, time.Now())
+
+	// cancelled flow can't be retried
+	if flow.Spec.Cancel && flow.Spec.RetryFrom != "" {
+		_, err := c.rejectRetry(flow, "cancelled flow can't be retried from stage %s", flow.Spec.RetryFrom)
 		return err
 	}
 
-	if attached {
-		return nil
+	if !flow.Spec.Cancel && !timedOut {
+		queued, err := c.syncConcurrency(flow, jobMap)
+		if err != nil {
+			return err
+		}
+
+		if queued {
+			return c.queueFlow(key, flow)
+		}
+	}
+
+	// timed out flow can be retried because retry resets its start time
+	if !flow.Spec.Cancel {
+		retried, err := c.retryFlow(flow, jobMap)
+		if err != nil {
+			return err
+		}
+
+		if retried {
+			return nil
+		}
+	}
+
+	// cancelled or timed out flow needs no mario
+	if !flow.Spec.Cancel && !timedOut {
+		attached, err := c.attachMario(flow, jobMap)
+		if err != nil {
+			return err
+		}
+
+		if attached {
+			return nil
+		}
 	}
 
 	pvc, err := c.pvcLister.PersistentVolumeClaims(ns).Get(name)
@@ -67,20 +121,49 @@ func (c *Controller) syncFlow(key string) error {
 		return err
 	}
 
-	if err := c.syncConfigMap(flow, cm); err != nil {
+	tokenSecret, err := c.secretLister.Secrets(ns).Get(marioTokenSecretName(flow))
+	if err != nil && !errors.IsNotFound(err) {
 		return err
 	}
 
-	if err := c.syncPVC(flow, pvc); err != nil {
-		return err
+	if flow.Spec.Cancel || timedOut {
+		if err := c.cancelJobs(flow, jobMap); err != nil {
+			return err
+		}
+	} else {
+		if err := c.syncConfigMap(flow, cm); err != nil {
+			return err
+		}
+
+		if err := c.syncPVC(flow, pvc); err != nil {
+			return err
+		}
+
+		if err := c.syncTokenSecret(flow, tokenSecret); err != nil {
+			return err
+		}
+
+		if err := c.syncJob(flow, jobMap); err != nil {
+			return err
+		}
 	}
 
-	if err := c.syncJob(flow, jobMap); err != nil {
+	updated, err := c.syncFlowStatus(flow, jobMap, pvc)
+	if err != nil {
 		return err
 	}
+	if updated != nil {
+		flow = updated
+	}
 
-	if _, err := c.syncFlowStatus(flow, jobMap, pvc); err != nil {
-		return err
+	switch flow.Status.Phase {
+	case v1alpha1.FlowSucceed, v1alpha1.FlowFailed, v1alpha1.FlowCancelled:
+		return nil
+	}
+
+	// requeue flow to mark it as timed out when deadline is exceeded
+	if left, ok := flowTimeLeft(flow, time.Now()); ok && left > 0 {
+		c.queue.AddAfter(key, left)
 	}
 
 	return nil
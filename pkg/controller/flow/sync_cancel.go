@@ -0,0 +1,72 @@
+package flow
+
+import (
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+// cancelJobs deletes all unfinished jobs of the cancelled flow,
+// finished jobs are kept to record history
+func (c *Controller) cancelJobs(flow *v1alpha1.Flow, jobMap map[string]*batchv1.Job) error {
+	policy := metav1.DeletePropagationBackground
+	for _, job := range jobMap {
+		if IsJobComplete(job) || IsJobFailed(job) {
+			continue
+		}
+		if job.DeletionTimestamp != nil {
+			continue
+		}
+
+		klog.Infof("flow %s/%s is cancelled, delete job %s", flow.Namespace, flow.Name, job.Name)
+
+		if err := c.kubeClient.BatchV1().Jobs(job.Namespace).Delete(job.Name, &metav1.DeleteOptions{
+			PropagationPolicy: &policy,
+		}); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cancelFlowStatus marks unfinished stages as cancelled and changes
+// phase of unfinished flow to cancelled
+func cancelFlowStatus(status *v1alpha1.FlowStatus) {
+	markUnfinishedStages(status, v1alpha1.StageJobCancelled)
+
+	switch status.Phase {
+	case v1alpha1.FlowSucceed, v1alpha1.FlowFailed:
+	default:
+		status.Phase = v1alpha1.FlowCancelled
+	}
+}
+
+// markUnfinishedStages changes phase of unfinished stages and their legs
+func markUnfinishedStages(status *v1alpha1.FlowStatus, phase string) {
+	for i := range status.StageStatuses {
+		s := &status.StageStatuses[i]
+		if isStageFinished(s.Phase) {
+			continue
+		}
+		s.Phase = phase
+
+		for k := range s.Legs {
+			leg := &s.Legs[k]
+			if !isStageFinished(leg.Phase) {
+				leg.Phase = phase
+			}
+		}
+	}
+}
+
+func isStageFinished(phase string) bool {
+	switch phase {
+	case v1alpha1.StageJobComplete, v1alpha1.StageJobFailed, v1alpha1.StageJobTimedOut, v1alpha1.StageSkipped:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package flow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	"github.com/liubog2008/oooops/pkg/client/clientset/fake"
+)
+
+func TestCancelJobs(t *testing.T) {
+	newJob := func(name string, condition batchv1.JobConditionType, deleting bool) *batchv1.Job {
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      name,
+			},
+		}
+		if condition != "" {
+			job.Status.Conditions = []batchv1.JobCondition{
+				{Type: condition, Status: corev1.ConditionTrue},
+			}
+		}
+		if deleting {
+			now := metav1.Now()
+			job.DeletionTimestamp = &now
+		}
+		return job
+	}
+
+	jobMap := map[string]*batchv1.Job{
+		"complete": newJob("complete", batchv1.JobComplete, false),
+		"failed":   newJob("failed", batchv1.JobFailed, false),
+		"running":  newJob("running", "", false),
+		"deleting": newJob("deleting", "", true),
+	}
+
+	client := kubefake.NewSimpleClientset()
+	for _, job := range jobMap {
+		_, err := client.BatchV1().Jobs(job.Namespace).Create(job)
+		assert.NoError(t, err)
+	}
+
+	c := &Controller{
+		kubeClient: client,
+	}
+	flow := &v1alpha1.Flow{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "test",
+		},
+	}
+	assert.NoError(t, c.cancelJobs(flow, jobMap))
+
+	jobs, err := client.BatchV1().Jobs("default").List(metav1.ListOptions{})
+	assert.NoError(t, err)
+
+	names := []string{}
+	for i := range jobs.Items {
+		names = append(names, jobs.Items[i].Name)
+	}
+	// finished jobs are kept and deleting jobs are not deleted again
+	assert.ElementsMatch(t, []string{"complete", "failed", "deleting"}, names)
+}
+
+func TestCancelFlowStatus(t *testing.T) {
+	status := &v1alpha1.FlowStatus{
+		Phase: v1alpha1.FlowRunning,
+		StageStatuses: []v1alpha1.StageStatus{
+			{Name: "complete", Phase: v1alpha1.StageJobComplete},
+			{Name: "skipped", Phase: v1alpha1.StageSkipped},
+			{
+				Name:  "running",
+				Phase: v1alpha1.StageJobRunning,
+				Legs: []v1alpha1.StageLeg{
+					{Phase: v1alpha1.StageJobFailed},
+					{Phase: v1alpha1.StageJobRunning},
+				},
+			},
+		},
+	}
+
+	cancelFlowStatus(status)
+	assert.Equal(t, v1alpha1.FlowCancelled, status.Phase)
+	assert.Equal(t, v1alpha1.StageJobComplete, status.StageStatuses[0].Phase)
+	assert.Equal(t, v1alpha1.StageSkipped, status.StageStatuses[1].Phase)
+	assert.Equal(t, v1alpha1.StageJobCancelled, status.StageStatuses[2].Phase)
+	assert.Equal(t, v1alpha1.StageJobFailed, status.StageStatuses[2].Legs[0].Phase)
+	assert.Equal(t, v1alpha1.StageJobCancelled, status.StageStatuses[2].Legs[1].Phase)
+
+	// finished flow is not cancelled
+	finished := &v1alpha1.FlowStatus{Phase: v1alpha1.FlowSucceed}
+	cancelFlowStatus(finished)
+	assert.Equal(t, v1alpha1.FlowSucceed, finished.Phase)
+}
+
+func TestRejectRetry(t *testing.T) {
+	flow := &v1alpha1.Flow{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "test",
+		},
+		Spec: v1alpha1.FlowSpec{
+			Cancel:    true,
+			RetryFrom: "build",
+		},
+	}
+	client := fake.NewSimpleClientset(flow)
+	recorder := record.NewFakeRecorder(10)
+	c := &Controller{
+		extClient:     client,
+		eventRecorder: recorder,
+	}
+
+	updated, err := c.rejectRetry(flow, "cancelled flow can't be retried from stage %s", flow.Spec.RetryFrom)
+	assert.NoError(t, err)
+	assert.True(t, updated)
+
+	got, err := client.MarioV1alpha1().Flows("default").Get("test", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, got.Spec.RetryFrom)
+	assert.Contains(t, <-recorder.Events, v1alpha1.FlowReasonInvalidRetry)
+}
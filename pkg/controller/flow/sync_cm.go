@@ -3,7 +3,8 @@ package flow
 import (
 	"bytes"
 	"fmt"
-	"html/template"
+	"strings"
+	"text/template"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -12,13 +13,65 @@ import (
 )
 
 const (
+	// refPrefix defines prefix of full refs
+	refPrefix = "refs/"
+
+	// gitScriptTemplateContent defines script to fetch code,
+	// credentials are read from the mounted git pull secret when the script runs.
+	// Full ref is fetched into the same local ref so that it can be resolved by mario.
+	// If base ref is set, ref will be merged into base ref with full history.
+	// Repo and refs are passed by env and always quoted so that they are never
+	// interpreted by shell.
 	gitScriptTemplateContent = `#!/bin/sh
 git init
-git remote add origin {{ .repo }}
-
-git fetch origin {{ .ref }} --depth=1
-
-git reset --hard FETCH_HEAD`
+git remote add origin -- "${{ .repoEnv }}"
+
+secret={{ .secretPath }}
+if [ -f "$secret/{{ .sshKey }}" ]; then
+  if [ ! -f "$secret/{{ .knownHosts }}" ]; then
+    echo "{{ .knownHosts }} is required to verify host when ssh key is used" >&2
+    exit 1
+  fi
+  export GIT_SSH_COMMAND="ssh -i $secret/{{ .sshKey }} -o IdentitiesOnly=yes -o UserKnownHostsFile=$secret/{{ .knownHosts }} -o StrictHostKeyChecking=yes"
+fi
+if [ -f "$secret/{{ .password }}" ]; then
+  git config --global credential.helper "!f() { echo username=\$(cat $secret/{{ .username }} 2>/dev/null || echo git); echo password=\$(cat $secret/{{ .password }}); }; f"
+fi
+
+{{ if .baseRef -}}
+git fetch --update-head-ok origin -- "+${{ .baseRefEnv }}:${{ .baseRefEnv }}"
+
+git reset --hard FETCH_HEAD
+
+git fetch origin -- "+${{ .refEnv }}:${{ .refEnv }}"
+
+git -c user.name={{ .mergeUser }} -c user.email={{ .mergeEmail }} merge --no-ff --no-edit FETCH_HEAD
+{{- else if .fullRef -}}
+git fetch --update-head-ok --depth=1 origin -- "+${{ .refEnv }}:${{ .refEnv }}"
+
+git reset --hard FETCH_HEAD
+{{- else -}}
+git fetch --depth=1 origin -- "${{ .refEnv }}"
+
+git reset --hard FETCH_HEAD
+{{- end }}`
+
+	// gitSecretPath defines path to mount git pull secret
+	gitSecretPath = "/etc/git-secret"
+	// gitSecretVolumeName defines volume name of git pull secret
+	gitSecretVolumeName = "git-secret"
+	// gitKnownHostsKey defines key of known hosts in git pull secret
+	gitKnownHostsKey = "known_hosts"
+
+	// gitMergeUser and gitMergeEmail define committer of merge commit
+	gitMergeUser  = "mario"
+	gitMergeEmail = "mario@oooops.com"
+
+	// gitRepoEnv, gitRefEnv and gitBaseRefEnv define envs of git container
+	// which pass repo and refs of flow to git script
+	gitRepoEnv    = "GIT_REPO"
+	gitRefEnv     = "GIT_REF"
+	gitBaseRefEnv = "GIT_BASE_REF"
 )
 
 var (
@@ -55,8 +108,20 @@ func (c *Controller) generateGitScript(flow *v1alpha1.Flow) (*corev1.ConfigMap,
 	buf := bytes.Buffer{}
 
 	if err := gitScriptTemplate.Execute(&buf, map[string]string{
-		"repo": flow.Spec.Git.Repo,
-		"ref":  flow.Spec.Git.Ref,
+		"repoEnv":    gitRepoEnv,
+		"refEnv":     gitRefEnv,
+		"baseRefEnv": gitBaseRefEnv,
+		"baseRef":    nonEmpty(flow.Spec.Git.BaseRef),
+		"fullRef":    fullRef(flow.Spec.Git.Ref),
+
+		"mergeUser":  gitMergeUser,
+		"mergeEmail": gitMergeEmail,
+
+		"secretPath": gitSecretPath,
+		"sshKey":     corev1.SSHAuthPrivateKey,
+		"knownHosts": gitKnownHostsKey,
+		"username":   corev1.BasicAuthUsernameKey,
+		"password":   corev1.BasicAuthPasswordKey,
 	}); err != nil {
 		return nil, err
 	}
@@ -76,3 +141,19 @@ func (c *Controller) generateGitScript(flow *v1alpha1.Flow) (*corev1.ConfigMap,
 
 	return &cm, nil
 }
+
+// nonEmpty returns "true" if s is not empty
+func nonEmpty(s string) string {
+	if s != "" {
+		return "true"
+	}
+	return ""
+}
+
+// fullRef returns "true" if ref is a full ref, e.g. refs/pull/1/head
+func fullRef(ref string) string {
+	if strings.HasPrefix(ref, refPrefix) {
+		return "true"
+	}
+	return ""
+}
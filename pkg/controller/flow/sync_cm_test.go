@@ -0,0 +1,126 @@
+package flow
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+func TestGenerateGitScript(t *testing.T) {
+	c := &Controller{
+		GroupVersionKind: v1alpha1.SchemeGroupVersion.WithKind("Flow"),
+	}
+	flow := &v1alpha1.Flow{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "test",
+		},
+		Spec: v1alpha1.FlowSpec{
+			Git: v1alpha1.Git{
+				Repo: "git@github.com:liubog2008/oooops.git",
+				Ref:  "refs/heads/master",
+			},
+		},
+	}
+
+	cm, err := c.generateGitScript(flow)
+	assert.NoError(t, err)
+
+	script := cm.Data[gitScriptName]
+	assert.Contains(t, script, `git remote add origin -- "$GIT_REPO"`)
+	assert.NotContains(t, script, flow.Spec.Git.Repo)
+	assert.Contains(t, script, "UserKnownHostsFile=$secret/known_hosts")
+	assert.Contains(t, script, "secret="+gitSecretPath)
+	assert.Contains(t, script, "credential.helper")
+
+	if _, err := exec.LookPath("sh"); err == nil {
+		cmd := exec.Command("sh", "-n")
+		cmd.Stdin = strings.NewReader(script)
+		out, err := cmd.CombinedOutput()
+		assert.NoError(t, err, string(out))
+	}
+}
+
+func TestGenerateMergeGitScript(t *testing.T) {
+	c := &Controller{
+		GroupVersionKind: v1alpha1.SchemeGroupVersion.WithKind("Flow"),
+	}
+	flow := &v1alpha1.Flow{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "test",
+		},
+		Spec: v1alpha1.FlowSpec{
+			Git: v1alpha1.Git{
+				Repo:    "https://github.com/liubog2008/oooops.git",
+				Ref:     "refs/pull/12/head",
+				BaseRef: "refs/heads/master",
+			},
+		},
+	}
+
+	cm, err := c.generateGitScript(flow)
+	assert.NoError(t, err)
+
+	script := cm.Data[gitScriptName]
+	assert.Contains(t, script, `git fetch --update-head-ok origin -- "+$GIT_BASE_REF:$GIT_BASE_REF"`+"\n")
+	assert.Contains(t, script, `git fetch origin -- "+$GIT_REF:$GIT_REF"`+"\n")
+	assert.Contains(t, script, "merge --no-ff --no-edit FETCH_HEAD")
+
+	flow.Spec.Git.BaseRef = ""
+	cm, err = c.generateGitScript(flow)
+	assert.NoError(t, err)
+
+	script = cm.Data[gitScriptName]
+	assert.Contains(t, script, `git fetch --update-head-ok --depth=1 origin -- "+$GIT_REF:$GIT_REF"`)
+	assert.NotContains(t, script, "merge")
+
+	if _, err := exec.LookPath("sh"); err == nil {
+		cmd := exec.Command("sh", "-n")
+		cmd.Stdin = strings.NewReader(script)
+		out, err := cmd.CombinedOutput()
+		assert.NoError(t, err, string(out))
+	}
+}
+
+func TestGitScriptNotInjected(t *testing.T) {
+	c := &Controller{
+		GroupVersionKind: v1alpha1.SchemeGroupVersion.WithKind("Flow"),
+	}
+	flow := &v1alpha1.Flow{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "test",
+		},
+		Spec: v1alpha1.FlowSpec{
+			Git: v1alpha1.Git{
+				Repo:    "https://github.com/liubog2008/oooops.git;id",
+				Ref:     "refs/heads/$(id)",
+				BaseRef: "refs/heads/`id`",
+			},
+			Selector: &metav1.LabelSelector{},
+		},
+	}
+
+	cm, err := c.generateGitScript(flow)
+	assert.NoError(t, err)
+
+	script := cm.Data[gitScriptName]
+	assert.NotContains(t, script, "id")
+
+	job := c.generateGitJob(flow)
+	env := map[string]string{}
+	for _, e := range job.Spec.Template.Spec.Containers[0].Env {
+		env[e.Name] = e.Value
+	}
+	assert.Equal(t, map[string]string{
+		gitRepoEnv:    flow.Spec.Git.Repo,
+		gitRefEnv:     flow.Spec.Git.Ref,
+		gitBaseRefEnv: flow.Spec.Git.BaseRef,
+	}, env)
+}
@@ -0,0 +1,164 @@
+package flow
+
+import (
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+const (
+	// flowReasonSuperseded is reason of event when flow is cancelled by a newer flow
+	flowReasonSuperseded = "Superseded"
+)
+
+// isFlowFinished returns true if flow is succeeded, failed or cancelled
+func isFlowFinished(flow *v1alpha1.Flow) bool {
+	return isFinishedPhase(flow.Status.Phase)
+}
+
+// isFinishedPhase returns true if phase is succeeded, failed or cancelled
+func isFinishedPhase(phase string) bool {
+	switch phase {
+	case v1alpha1.FlowSucceed, v1alpha1.FlowFailed, v1alpha1.FlowCancelled:
+		return true
+	}
+	return false
+}
+
+// isFlowStarted returns true if flow has been started. Phase can't be used
+// because it is still pending when git and mario jobs are running
+func isFlowStarted(flow *v1alpha1.Flow, jobMap map[string]*batchv1.Job) bool {
+	return flow.Status.StartTime != nil || len(jobMap) != 0
+}
+
+// isCreatedBefore returns true if a is created before b,
+// name is compared if they are created at the same time
+func isCreatedBefore(a, b *v1alpha1.Flow) bool {
+	if a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.Name < b.Name
+	}
+	return a.CreationTimestamp.Before(&b.CreationTimestamp)
+}
+
+// listConcurrentFlows returns other unfinished flows in the same concurrency group
+func (c *Controller) listConcurrentFlows(flow *v1alpha1.Flow) ([]*v1alpha1.Flow, error) {
+	if flow.Spec.ConcurrencyGroup == "" {
+		return nil, nil
+	}
+	flows, err := c.flowLister.Flows(flow.Namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	concurrent := []*v1alpha1.Flow{}
+	for _, other := range flows {
+		if other.Name == flow.Name || other.DeletionTimestamp != nil {
+			continue
+		}
+		if other.Spec.ConcurrencyGroup != flow.Spec.ConcurrencyGroup || isFlowFinished(other) {
+			continue
+		}
+		concurrent = append(concurrent, other)
+	}
+	return concurrent, nil
+}
+
+// syncConcurrency applies concurrency policy of flow, it returns true
+// if flow should wait for earlier flows in the same group.
+// Earlier flows will be cancelled if policy is Replace
+func (c *Controller) syncConcurrency(flow *v1alpha1.Flow, jobMap map[string]*batchv1.Job) (bool, error) {
+	switch flow.Spec.ConcurrencyPolicy {
+	case v1alpha1.ForbidConcurrent, v1alpha1.ReplaceConcurrent:
+	default:
+		return false, nil
+	}
+
+	flows, err := c.listConcurrentFlows(flow)
+	if err != nil {
+		return false, err
+	}
+
+	queued := false
+	for _, other := range flows {
+		if !isCreatedBefore(other, flow) {
+			continue
+		}
+
+		if flow.Spec.ConcurrencyPolicy == v1alpha1.ForbidConcurrent {
+			// started flow will not be queued again
+			queued = !isFlowStarted(flow, jobMap)
+			continue
+		}
+
+		if other.Spec.Cancel {
+			continue
+		}
+
+		klog.Infof("flow %s/%s is superseded by %s, cancel it", other.Namespace, other.Name, flow.Name)
+
+		updating := other.DeepCopy()
+		updating.Spec.Cancel = true
+		if _, err := c.extClient.MarioV1alpha1().Flows(other.Namespace).Update(updating); err != nil {
+			return false, err
+		}
+		c.eventRecorder.Eventf(other, corev1.EventTypeNormal, flowReasonSuperseded,
+			"Flow is cancelled because it is superseded by flow %s", flow.Name)
+	}
+
+	return queued, nil
+}
+
+// queueFlow marks flow as queued, it will be requeued when
+// earlier flows are finished or it exceeds its timeout
+func (c *Controller) queueFlow(key string, flow *v1alpha1.Flow) error {
+	if left, ok := flowTimeLeft(flow, time.Now()); ok && left > 0 {
+		c.queue.AddAfter(key, left)
+	}
+
+	if flow.Status.Phase == v1alpha1.FlowQueued {
+		return nil
+	}
+
+	updating := v1alpha1.Flow{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       flow.Namespace,
+			Name:            flow.Name,
+			ResourceVersion: flow.ResourceVersion,
+		},
+		Status: v1alpha1.FlowStatus{
+			Phase: v1alpha1.FlowQueued,
+		},
+	}
+
+	if _, err := c.extClient.MarioV1alpha1().Flows(updating.Namespace).UpdateStatus(&updating); err != nil {
+		return err
+	}
+	return nil
+}
+
+// enqueueConcurrentFlows enqueues flows in the same concurrency group,
+// it is called when flow is finished or deleted so that queued flows can be started
+func (c *Controller) enqueueConcurrentFlows(flow *v1alpha1.Flow) {
+	if flow.Spec.ConcurrencyPolicy != v1alpha1.ForbidConcurrent {
+		return
+	}
+	flows, err := c.listConcurrentFlows(flow)
+	if err != nil {
+		klog.Errorf("can't list concurrent flows of %s/%s: %v", flow.Namespace, flow.Name, err)
+		return
+	}
+	for _, other := range flows {
+		key, err := cache.MetaNamespaceKeyFunc(other)
+		if err != nil {
+			continue
+		}
+		c.queue.Add(key)
+	}
+}
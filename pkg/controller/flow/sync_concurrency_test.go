@@ -0,0 +1,134 @@
+package flow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	"github.com/liubog2008/oooops/pkg/client/clientset/fake"
+	mariolisters "github.com/liubog2008/oooops/pkg/client/listers/mario/v1alpha1"
+)
+
+func TestSyncConcurrency(t *testing.T) {
+	now := time.Now()
+
+	newFlow := func(name string, created time.Time, phase string, policy v1alpha1.ConcurrencyPolicy) *v1alpha1.Flow {
+		return &v1alpha1.Flow{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:         "default",
+				Name:              name,
+				CreationTimestamp: metav1.NewTime(created),
+			},
+			Spec: v1alpha1.FlowSpec{
+				ConcurrencyPolicy: policy,
+				ConcurrencyGroup:  "ci@refs/heads/master",
+			},
+			Status: v1alpha1.FlowStatus{
+				Phase: phase,
+			},
+		}
+	}
+
+	started := newFlow("c", now, v1alpha1.FlowPending, v1alpha1.ForbidConcurrent)
+	started.Status.StartTime = &metav1.Time{Time: now}
+
+	cases := []struct {
+		desc      string
+		flow      *v1alpha1.Flow
+		jobMap    map[string]*batchv1.Job
+		others    []*v1alpha1.Flow
+		queued    bool
+		cancelled []string
+	}{
+		{
+			desc: "allow",
+			flow: newFlow("c", now, v1alpha1.FlowPending, v1alpha1.AllowConcurrent),
+			others: []*v1alpha1.Flow{
+				newFlow("a", now.Add(-time.Minute), v1alpha1.FlowRunning, v1alpha1.AllowConcurrent),
+			},
+		},
+		{
+			desc: "forbid queues new flow",
+			flow: newFlow("c", now, v1alpha1.FlowPending, v1alpha1.ForbidConcurrent),
+			others: []*v1alpha1.Flow{
+				newFlow("a", now.Add(-time.Minute), v1alpha1.FlowRunning, v1alpha1.ForbidConcurrent),
+			},
+			queued: true,
+		},
+		{
+			desc: "forbid doesn't queue pending flow whose jobs are running",
+			flow: newFlow("c", now, v1alpha1.FlowPending, v1alpha1.ForbidConcurrent),
+			jobMap: map[string]*batchv1.Job{
+				v1alpha1.FlowStageGit: {},
+			},
+			others: []*v1alpha1.Flow{
+				newFlow("a", now.Add(-time.Minute), v1alpha1.FlowRunning, v1alpha1.ForbidConcurrent),
+			},
+		},
+		{
+			desc: "forbid doesn't queue started flow",
+			flow: started,
+			others: []*v1alpha1.Flow{
+				newFlow("a", now.Add(-time.Minute), v1alpha1.FlowRunning, v1alpha1.ForbidConcurrent),
+			},
+		},
+		{
+			desc: "forbid starts flow after earlier flows are finished",
+			flow: newFlow("c", now, v1alpha1.FlowQueued, v1alpha1.ForbidConcurrent),
+			others: []*v1alpha1.Flow{
+				newFlow("a", now.Add(-time.Minute), v1alpha1.FlowSucceed, v1alpha1.ForbidConcurrent),
+				newFlow("d", now.Add(time.Minute), v1alpha1.FlowQueued, v1alpha1.ForbidConcurrent),
+			},
+		},
+		{
+			desc: "replace cancels earlier flows",
+			flow: newFlow("c", now, v1alpha1.FlowPending, v1alpha1.ReplaceConcurrent),
+			others: []*v1alpha1.Flow{
+				newFlow("a", now.Add(-time.Minute), v1alpha1.FlowRunning, v1alpha1.ReplaceConcurrent),
+				newFlow("b", now.Add(-time.Minute), v1alpha1.FlowFailed, v1alpha1.ReplaceConcurrent),
+				newFlow("d", now.Add(time.Minute), v1alpha1.FlowPending, v1alpha1.ReplaceConcurrent),
+			},
+			cancelled: []string{"a"},
+		},
+	}
+
+	for _, c := range cases {
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+			cache.NamespaceIndex: cache.MetaNamespaceIndexFunc,
+		})
+		client := fake.NewSimpleClientset()
+		assert.NoError(t, indexer.Add(c.flow), c.desc)
+		for _, other := range c.others {
+			assert.NoError(t, indexer.Add(other), c.desc)
+			_, err := client.MarioV1alpha1().Flows(other.Namespace).Create(other)
+			assert.NoError(t, err, c.desc)
+		}
+
+		ctrl := &Controller{
+			extClient:     client,
+			flowLister:    mariolisters.NewFlowLister(indexer),
+			eventRecorder: record.NewFakeRecorder(10),
+		}
+
+		queued, err := ctrl.syncConcurrency(c.flow, c.jobMap)
+		assert.NoError(t, err, c.desc)
+		assert.Equal(t, c.queued, queued, c.desc)
+
+		flows, err := client.MarioV1alpha1().Flows("default").List(metav1.ListOptions{})
+		assert.NoError(t, err, c.desc)
+
+		cancelled := []string{}
+		for i := range flows.Items {
+			if flows.Items[i].Spec.Cancel {
+				cancelled = append(cancelled, flows.Items[i].Name)
+			}
+		}
+		assert.ElementsMatch(t, c.cancelled, cancelled, c.desc)
+	}
+}
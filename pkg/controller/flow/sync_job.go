@@ -1,8 +1,8 @@
 package flow
 
 import (
-	"fmt"
 	"path/filepath"
+	"time"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -10,6 +10,8 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	"github.com/liubog2008/oooops/pkg/mario/stages"
+	"github.com/liubog2008/oooops/pkg/mario/template"
 )
 
 const (
@@ -40,60 +42,109 @@ func (c *Controller) syncJob(flow *v1alpha1.Flow, jobMap map[string]*batchv1.Job
 		return nil
 	}
 
-	job, err := c.generateNextJob(flow, jobMap)
+	graph, err := stages.NewGraph(flow.Spec.Stages)
 	if err != nil {
-		return err
-	}
-
-	if job == nil {
+		// status will be updated to failed, no need to retry
+		c.eventRecorder.Eventf(flow, corev1.EventTypeWarning, v1alpha1.FlowReasonInvalidDependency, "%v", err)
 		return nil
 	}
 
-	if _, err := c.kubeClient.BatchV1().Jobs(flow.Namespace).Create(job); err != nil {
+	jobs, err := c.generateNextJobs(flow, graph, jobMap)
+	if err != nil {
 		return err
 	}
 
+	for _, job := range jobs {
+		if _, err := c.kubeClient.BatchV1().Jobs(flow.Namespace).Create(job); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (c *Controller) generateNextJob(flow *v1alpha1.Flow, jobMap map[string]*batchv1.Job) (*batchv1.Job, error) {
-	last := -1
+// generateNextJobs returns jobs of stages whose dependencies have been completed
+func (c *Controller) generateNextJobs(
+	flow *v1alpha1.Flow,
+	graph *stages.Graph,
+	jobMap map[string]*batchv1.Job,
+) ([]*batchv1.Job, error) {
 	for i := range flow.Spec.Stages {
 		stage := &flow.Spec.Stages[i]
 
-		_, ok := jobMap[v1alpha1.UserJobPrefix+stage.Name]
-		if ok {
-			last = i
+		for _, leg := range expandMatrix(stage) {
+			job, ok := jobMap[leg.key(stage)]
+			// stop generating jobs if any stage is failed
+			if ok && IsJobFailed(job) {
+				return nil, nil
+			}
 		}
 	}
-	// all jobs have been generated
-	if last == len(flow.Spec.Stages)-1 {
-		return nil, nil
-	}
 
-	if last != -1 {
-		lastStage := &flow.Spec.Stages[last]
+	started, completed := stageProgress(flow, jobMap)
 
-		// existence has checked
-		lastJob := jobMap[v1alpha1.UserJobPrefix+lastStage.Name]
+	jobs := []*batchv1.Job{}
+	for _, index := range graph.Runnable(started, completed) {
+		stage := &flow.Spec.Stages[index]
 
-		if !IsJobComplete(lastJob) {
-			return nil, nil
+		if err := c.checkStage(flow, stage); err != nil {
+			if e, ok := err.(*stageError); ok {
+				// status will be updated to failed, no need to retry
+				c.eventRecorder.Eventf(flow, corev1.EventTypeWarning, e.reason, "stage %s: %v", stage.Name, e)
+				return nil, nil
+			}
+			return nil, err
 		}
-	}
 
-	curIndex := last + 1
+		for _, leg := range expandMatrix(stage) {
+			if _, ok := jobMap[leg.key(stage)]; ok {
+				continue
+			}
+			job, err := c.generateActionJob(flow, index, &leg)
+			if err != nil {
+				return nil, err
+			}
+			if job == nil {
+				continue
+			}
+			jobs = append(jobs, job)
+		}
+	}
 
-	// NOTE: len of status stages are always less than len of spec stages
-	// TODO(liubog2008): add test case to test it
-	// only when last stage has been completed
-	// next job will be generated
-	job, err := c.generateActionJob(flow, curIndex)
+	return jobs, nil
+}
 
-	return job, err
+// stageProgress returns functions to check whether a stage is started or completed.
+// Stage is started only when jobs of all legs are created,
+// skipped stages are regarded as completed
+func stageProgress(flow *v1alpha1.Flow, jobMap map[string]*batchv1.Job) (started, completed func(*v1alpha1.Stage) bool) {
+	started = func(stage *v1alpha1.Stage) bool {
+		if isStageSkipped(flow, stage) {
+			return true
+		}
+		for _, leg := range expandMatrix(stage) {
+			if _, ok := jobMap[leg.key(stage)]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+	completed = func(stage *v1alpha1.Stage) bool {
+		if isStageSkipped(flow, stage) {
+			return true
+		}
+		for _, leg := range expandMatrix(stage) {
+			job, ok := jobMap[leg.key(stage)]
+			if !ok || !IsJobComplete(job) {
+				return false
+			}
+		}
+		return true
+	}
+	return started, completed
 }
 
-func (c *Controller) generateActionJob(flow *v1alpha1.Flow, stageIndex int) (*batchv1.Job, error) {
+func (c *Controller) generateActionJob(flow *v1alpha1.Flow, stageIndex int, leg *stageLeg) (*batchv1.Job, error) {
 	stage := flow.Spec.Stages[stageIndex]
 	mario := flow.Spec.Mario
 	owner := metav1.NewControllerRef(flow, c.GroupVersionKind)
@@ -117,14 +168,28 @@ func (c *Controller) generateActionJob(flow *v1alpha1.Flow, stageIndex int) (*ba
 
 		version := flow.Spec.Git.Ref
 
-		cs, err := constructContainers(action, version)
+		tmpl, err := c.resolveActionTemplate(flow.Namespace, mario, action)
+		if err != nil {
+			return nil, err
+		}
+
+		cs, err := constructContainers(action, tmpl, version, leg.params)
 		if err != nil {
 			return nil, err
 		}
 
+		// options of stage override options of action and template
+		opts := mergePodOptions(&tmpl.PodOptions, &action.PodOptions, &stage.PodOptions)
+		for k := range cs {
+			if opts.Resources != nil {
+				cs[k].Resources = *opts.Resources
+			}
+			cs[k].SecurityContext = opts.SecurityContext
+		}
+
 		job := batchv1.Job{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:      nameJoin(flow.Name, "user", stage.Name),
+				Name:      leg.jobName(flow, &stage),
 				Namespace: flow.Namespace,
 				Labels:    labels,
 				OwnerReferences: []metav1.OwnerReference{
@@ -132,11 +197,16 @@ func (c *Controller) generateActionJob(flow *v1alpha1.Flow, stageIndex int) (*ba
 				},
 			},
 			Spec: batchv1.JobSpec{
+				ActiveDeadlineSeconds: activeDeadlineSeconds(flow, stageTimeout(&stage, action), time.Now()),
 				Template: corev1.PodTemplateSpec{
 					Spec: corev1.PodSpec{
-						RestartPolicy: corev1.RestartPolicyNever,
-						Containers:    cs,
-						Volumes: []corev1.Volume{
+						ServiceAccountName: opts.ServiceAccountName,
+						NodeSelector:       opts.NodeSelector,
+						Tolerations:        opts.Tolerations,
+						Affinity:           opts.Affinity,
+						RestartPolicy:      corev1.RestartPolicyNever,
+						Containers:         cs,
+						Volumes: append([]corev1.Volume{
 							{
 								Name: gitRootVolumeName,
 								VolumeSource: corev1.VolumeSource{
@@ -145,7 +215,7 @@ func (c *Controller) generateActionJob(flow *v1alpha1.Flow, stageIndex int) (*ba
 									},
 								},
 							},
-						},
+						}, secretVolumes(action)...),
 					},
 				},
 			},
@@ -157,43 +227,32 @@ func (c *Controller) generateActionJob(flow *v1alpha1.Flow, stageIndex int) (*ba
 	return nil, nil
 }
 
-func constructContainers(action *v1alpha1.MarioAction, version string) ([]corev1.Container, error) {
-	// TODO(liubog2008): support action importing
-	if action.Template == nil {
-		return nil, fmt.Errorf("no action template")
-	}
-	env := make([]corev1.EnvVar, 0, len(action.Env)+1)
-	env = append(env, corev1.EnvVar{
-		Name:  action.Template.Version.EnvName,
-		Value: version,
-	})
-
-	for i := range action.Env {
-		e := &action.Env[i]
-		if e.Name == action.Template.Version.EnvName {
-			return nil, fmt.Errorf("set an env whose name is confict with version env")
-		}
-		env = append(env, corev1.EnvVar{
-			Name:  e.Name,
-			Value: e.Value,
-		})
+func constructContainers(
+	action *v1alpha1.MarioAction,
+	tmpl *v1alpha1.ActionTemplate,
+	version string,
+	params []corev1.EnvVar,
+) ([]corev1.Container, error) {
+	env, err := template.Env(action, tmpl, version, params)
+	if err != nil {
+		return nil, err
 	}
 
 	c := corev1.Container{
 		Name:       action.Name,
-		Image:      action.Template.Image,
-		Command:    action.Template.Command,
-		Args:       action.Template.Args,
-		WorkingDir: action.Template.WorkingDir,
+		Image:      tmpl.Image,
+		Command:    tmpl.Command,
+		Args:       tmpl.Args,
+		WorkingDir: tmpl.WorkingDir,
 
 		Env: env,
 
-		VolumeMounts: []corev1.VolumeMount{
+		VolumeMounts: append([]corev1.VolumeMount{
 			{
 				Name:      gitRootVolumeName,
-				MountPath: action.Template.WorkingDir,
+				MountPath: tmpl.WorkingDir,
 			},
-		},
+		}, secretVolumeMounts(action)...),
 	}
 
 	return []corev1.Container{c}, nil
@@ -204,7 +263,7 @@ func (c *Controller) generateGitJob(flow *v1alpha1.Flow) *batchv1.Job {
 
 	fileMode := int32(0755)
 
-	return &batchv1.Job{
+	job := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      flow.Name + "-git",
 			Namespace: flow.Namespace,
@@ -214,6 +273,7 @@ func (c *Controller) generateGitJob(flow *v1alpha1.Flow) *batchv1.Job {
 			Labels: flow.Spec.Selector.MatchLabels,
 		},
 		Spec: batchv1.JobSpec{
+			ActiveDeadlineSeconds: activeDeadlineSeconds(flow, nil, time.Now()),
 			Template: corev1.PodTemplateSpec{
 				Spec: corev1.PodSpec{
 					RestartPolicy: corev1.RestartPolicyNever,
@@ -225,6 +285,20 @@ func (c *Controller) generateGitJob(flow *v1alpha1.Flow) *batchv1.Job {
 								filepath.Join(gitScriptPath, gitScriptName),
 							},
 							WorkingDir: marioWorkingDir,
+							Env: []corev1.EnvVar{
+								{
+									Name:  gitRepoEnv,
+									Value: flow.Spec.Git.Repo,
+								},
+								{
+									Name:  gitRefEnv,
+									Value: flow.Spec.Git.Ref,
+								},
+								{
+									Name:  gitBaseRefEnv,
+									Value: flow.Spec.Git.BaseRef,
+								},
+							},
 
 							VolumeMounts: []corev1.VolumeMount{
 								{
@@ -264,23 +338,55 @@ func (c *Controller) generateGitJob(flow *v1alpha1.Flow) *batchv1.Job {
 			},
 		},
 	}
+
+	// credentials are only mounted into git job
+	if secret := flow.Spec.Git.GitPullSecret.Name; secret != "" {
+		secretMode := int32(0400)
+
+		spec := &job.Spec.Template.Spec
+		spec.Volumes = append(spec.Volumes, corev1.Volume{
+			Name: gitSecretVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName:  secret,
+					DefaultMode: &secretMode,
+				},
+			},
+		})
+		spec.Containers[0].VolumeMounts = append(spec.Containers[0].VolumeMounts, corev1.VolumeMount{
+			Name:      gitSecretVolumeName,
+			MountPath: gitSecretPath,
+			ReadOnly:  true,
+		})
+	}
+
+	return job
 }
 
 func (c *Controller) generateMarioJob(flow *v1alpha1.Flow) *batchv1.Job {
 	owner := metav1.NewControllerRef(flow, c.GroupVersionKind)
 
+	ref := flow.Spec.Git.Ref
+	if flow.Spec.Git.BaseRef != "" {
+		// merge commit only exists in the workspace
+		ref = "HEAD"
+	}
+
 	command := []string{
 		"/app/mario",
 		"--remote",
 		flow.Spec.Git.Repo,
 		"--ref",
-		flow.Spec.Git.Ref,
+		ref,
 		"--addr",
 		":8080",
-		"--token",
-		"test",
+		// token is read from file so that it never appears in spec of pod
+		"--token-file",
+		filepath.Join(marioTokenPath, marioTokenKey),
 	}
 
+	secretMode := int32(0400)
+
 	labels := map[string]string{}
 	for k, v := range flow.Spec.Selector.MatchLabels {
 		labels[k] = v
@@ -298,6 +404,7 @@ func (c *Controller) generateMarioJob(flow *v1alpha1.Flow) *batchv1.Job {
 			Labels: labels,
 		},
 		Spec: batchv1.JobSpec{
+			ActiveDeadlineSeconds: activeDeadlineSeconds(flow, nil, time.Now()),
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: labels,
@@ -317,6 +424,11 @@ func (c *Controller) generateMarioJob(flow *v1alpha1.Flow) *batchv1.Job {
 									Name:      gitRootVolumeName,
 									MountPath: marioWorkingDir,
 								},
+								{
+									Name:      marioTokenVolumeName,
+									MountPath: marioTokenPath,
+									ReadOnly:  true,
+								},
 							},
 							ReadinessProbe: &corev1.Probe{
 								Handler: corev1.Handler{
@@ -337,6 +449,15 @@ func (c *Controller) generateMarioJob(flow *v1alpha1.Flow) *batchv1.Job {
 								},
 							},
 						},
+						{
+							Name: marioTokenVolumeName,
+							VolumeSource: corev1.VolumeSource{
+								Secret: &corev1.SecretVolumeSource{
+									SecretName:  marioTokenSecretName(flow),
+									DefaultMode: &secretMode,
+								},
+							},
+						},
 					},
 				},
 			},
@@ -13,7 +13,7 @@ func (c *Controller) syncPVC(flow *v1alpha1.Flow, pvc *corev1.PersistentVolumeCl
 	if pvc != nil {
 		if !metav1.IsControlledBy(pvc, flow) {
 			// TODO(liubog2008): fix pvc name conflict
-			return fmt.Errorf("can't create pvc %s/%s, it exists and is not controlled by flow")
+			return fmt.Errorf("can't create pvc %s/%s, it exists and is not controlled by flow", pvc.Namespace, pvc.Name)
 		}
 
 		// NOTE(liubog2008): handle updation of pvc?
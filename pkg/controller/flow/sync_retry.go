@@ -0,0 +1,150 @@
+package flow
+
+import (
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	"github.com/liubog2008/oooops/pkg/mario/stages"
+)
+
+// retryFlow deletes jobs of the stage which will be retried and all stages
+// depending on it, the deleted jobs are recorded as attempts of stages.
+// It returns true if flow is updated
+func (c *Controller) retryFlow(flow *v1alpha1.Flow, jobMap map[string]*batchv1.Job) (bool, error) {
+	if flow.Spec.RetryFrom == "" {
+		return false, nil
+	}
+
+	graph, err := stages.NewGraph(flow.Spec.Stages)
+	if err != nil {
+		return c.rejectRetry(flow, "stages of flow are invalid: %v", err)
+	}
+
+	indexes, ok := graph.Downstream(flow.Spec.RetryFrom)
+	if !ok {
+		return c.rejectRetry(flow, "stage %s to retry is not found", flow.Spec.RetryFrom)
+	}
+
+	// running jobs should not be killed by retry
+	if !canRetry(flow) {
+		return c.rejectRetry(flow, "stage %s can't be retried before it fails or flow is finished", flow.Spec.RetryFrom)
+	}
+
+	status := flow.Status.DeepCopy()
+	jobs := []*batchv1.Job{}
+	for _, index := range indexes {
+		stage := &flow.Spec.Stages[index]
+		for _, leg := range expandMatrix(stage) {
+			job, ok := jobMap[leg.key(stage)]
+			if !ok {
+				continue
+			}
+			jobs = append(jobs, job)
+
+			recordAttempt(status, stage.Name, job)
+		}
+	}
+
+	// timeout of retried flow is measured from now
+	now := metav1.Now()
+	status.StartTime = &now
+
+	// attempts must be recorded before jobs are deleted
+	updating := v1alpha1.Flow{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       flow.Namespace,
+			Name:            flow.Name,
+			ResourceVersion: flow.ResourceVersion,
+		},
+		Status: *status,
+	}
+	updated, err := c.extClient.MarioV1alpha1().Flows(flow.Namespace).UpdateStatus(&updating)
+	if err != nil {
+		return false, err
+	}
+	flow = flow.DeepCopy()
+	flow.ResourceVersion = updated.ResourceVersion
+	flow.Status = updated.Status
+
+	policy := metav1.DeletePropagationBackground
+	for _, job := range jobs {
+		klog.Infof("retry stage %s of flow %s/%s, delete job %s", flow.Spec.RetryFrom, flow.Namespace, flow.Name, job.Name)
+
+		if err := c.kubeClient.BatchV1().Jobs(job.Namespace).Delete(job.Name, &metav1.DeleteOptions{
+			PropagationPolicy: &policy,
+		}); err != nil && !errors.IsNotFound(err) {
+			return false, err
+		}
+	}
+
+	return c.resetRetry(flow)
+}
+
+// canRetry returns true if the stage to retry is failed or timed out,
+// any stage of a finished flow can be retried
+func canRetry(flow *v1alpha1.Flow) bool {
+	if isFlowFinished(flow) {
+		return true
+	}
+	for i := range flow.Status.StageStatuses {
+		s := &flow.Status.StageStatuses[i]
+		if s.Name != flow.Spec.RetryFrom {
+			continue
+		}
+		switch s.Phase {
+		case v1alpha1.StageJobFailed, v1alpha1.StageJobTimedOut:
+			return true
+		}
+	}
+	return false
+}
+
+// rejectRetry records why flow can't be retried and clears retryFrom,
+// so that retry will not be handled again
+func (c *Controller) rejectRetry(flow *v1alpha1.Flow, format string, args ...interface{}) (bool, error) {
+	c.eventRecorder.Eventf(flow, corev1.EventTypeWarning, v1alpha1.FlowReasonInvalidRetry, format, args...)
+	return c.resetRetry(flow)
+}
+
+func (c *Controller) resetRetry(flow *v1alpha1.Flow) (bool, error) {
+	updating := flow.DeepCopy()
+	updating.Spec.RetryFrom = ""
+	if _, err := c.extClient.MarioV1alpha1().Flows(flow.Namespace).Update(updating); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// recordAttempt adds job as an attempt of stage into status,
+// attempt which has been recorded is ignored
+func recordAttempt(status *v1alpha1.FlowStatus, stage string, job *batchv1.Job) {
+	attempt := v1alpha1.StageAttempt{
+		Job:       job.Name,
+		Phase:     jobPhase(job),
+		StartTime: job.CreationTimestamp,
+	}
+
+	for i := range status.StageStatuses {
+		s := &status.StageStatuses[i]
+		if s.Name != stage {
+			continue
+		}
+		for k := range s.Attempts {
+			a := &s.Attempts[k]
+			if a.Job == attempt.Job && a.StartTime.Equal(&attempt.StartTime) {
+				return
+			}
+		}
+		s.Attempts = append(s.Attempts, attempt)
+		return
+	}
+
+	status.StageStatuses = append(status.StageStatuses, v1alpha1.StageStatus{
+		Name:     stage,
+		Attempts: []v1alpha1.StageAttempt{attempt},
+	})
+}
@@ -0,0 +1,37 @@
+package flow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+func TestCanRetry(t *testing.T) {
+	newFlow := func(phase, stagePhase string) *v1alpha1.Flow {
+		return &v1alpha1.Flow{
+			Spec: v1alpha1.FlowSpec{
+				RetryFrom: "build",
+			},
+			Status: v1alpha1.FlowStatus{
+				Phase: phase,
+				StageStatuses: []v1alpha1.StageStatus{
+					{Name: "build", Phase: stagePhase},
+				},
+			},
+		}
+	}
+
+	assert.True(t, canRetry(newFlow(v1alpha1.FlowRunning, v1alpha1.StageJobFailed)))
+	assert.True(t, canRetry(newFlow(v1alpha1.FlowRunning, v1alpha1.StageJobTimedOut)))
+	assert.True(t, canRetry(newFlow(v1alpha1.FlowSucceed, v1alpha1.StageJobComplete)))
+	assert.False(t, canRetry(newFlow(v1alpha1.FlowRunning, v1alpha1.StageJobRunning)))
+	assert.False(t, canRetry(newFlow(v1alpha1.FlowRunning, v1alpha1.StageJobComplete)))
+	assert.False(t, canRetry(&v1alpha1.Flow{
+		Spec: v1alpha1.FlowSpec{RetryFrom: "build"},
+		Status: v1alpha1.FlowStatus{
+			Phase: v1alpha1.FlowRunning,
+		},
+	}), "stage which is not started can't be retried")
+}
@@ -3,12 +3,14 @@ package flow
 import (
 	"fmt"
 	"reflect"
+	"time"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	"github.com/liubog2008/oooops/pkg/mario/stages"
 )
 
 func (c *Controller) syncFlowStatus(flow *v1alpha1.Flow, jobMap map[string]*batchv1.Job, pvc *corev1.PersistentVolumeClaim) (*v1alpha1.Flow, error) {
@@ -17,6 +19,30 @@ func (c *Controller) syncFlowStatus(flow *v1alpha1.Flow, jobMap map[string]*batc
 		return nil, err
 	}
 
+	if flow.Spec.Cancel {
+		cancelFlowStatus(status)
+	} else if isFlowTimedOut(flow, time.Now()) {
+		timeoutFlowStatus(status)
+	}
+
+	// start time is kept until flow is retried
+	status.StartTime = flow.Status.StartTime
+	if status.StartTime == nil {
+		now := metav1.Now()
+		status.StartTime = &now
+	}
+
+	// completion time is kept after flow is finished and cleared when it runs again
+	if isFinishedPhase(status.Phase) {
+		status.CompletionTime = flow.Status.CompletionTime
+		if status.CompletionTime == nil {
+			now := metav1.Now()
+			status.CompletionTime = &now
+		}
+	} else {
+		status.CompletionTime = nil
+	}
+
 	// TODO(liubog2008): optimize this function
 	if reflect.DeepEqual(status, &flow.Status) {
 		return nil, nil
@@ -39,44 +65,178 @@ func (c *Controller) syncFlowStatus(flow *v1alpha1.Flow, jobMap map[string]*batc
 	return updated, nil
 }
 
-func (c *Controller) calculateStageStatus(stages []v1alpha1.Stage, jobMap map[string]*batchv1.Job) ([]v1alpha1.StageStatus, error) {
+// failFlow marks flow as failed with condition, it is used when flow
+// can't be run at all, e.g. flow is invalid
+func (c *Controller) failFlow(flow *v1alpha1.Flow, cond *v1alpha1.FlowCondition) error {
+	if c := getFlowCondition(&flow.Status, cond.Type); c != nil && flow.Status.Phase == v1alpha1.FlowFailed &&
+		c.Status == cond.Status && c.Reason == cond.Reason && c.Message == cond.Message {
+		return nil
+	}
+
+	status := flow.Status.DeepCopy()
+	status.Phase = v1alpha1.FlowFailed
+	if status.CompletionTime == nil {
+		now := metav1.Now()
+		status.CompletionTime = &now
+	}
+	setFlowCondition(status, cond)
+
+	updating := v1alpha1.Flow{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       flow.Namespace,
+			Name:            flow.Name,
+			ResourceVersion: flow.ResourceVersion,
+		},
+		Status: *status,
+	}
+
+	if _, err := c.extClient.MarioV1alpha1().Flows(updating.Namespace).UpdateStatus(&updating); err != nil {
+		return err
+	}
+	return nil
+}
+
+// calculateStageStatus returns status of every stage in order of spec,
+// attempts of stages are copied from the previous status
+func (c *Controller) calculateStageStatus(
+	flow *v1alpha1.Flow,
+	jobMap map[string]*batchv1.Job,
+) ([]v1alpha1.StageStatus, error) {
+	attempts := map[string][]v1alpha1.StageAttempt{}
+	for i := range flow.Status.StageStatuses {
+		s := &flow.Status.StageStatuses[i]
+		attempts[s.Name] = s.Attempts
+	}
+
 	stageStatuses := []v1alpha1.StageStatus{}
-	missingCount := 0
-	for i := range stages {
-		stage := &stages[i]
+	for i := range flow.Spec.Stages {
+		stage := &flow.Spec.Stages[i]
+
+		if len(stage.Matrix) != 0 {
+			status := calculateMatrixStageStatus(flow, stage, jobMap)
+			status.Attempts = attempts[stage.Name]
+			stageStatuses = append(stageStatuses, status)
+			continue
+		}
 
 		job, ok := jobMap[v1alpha1.UserJobPrefix+stage.Name]
+		if !ok && isStageSkipped(flow, stage) {
+			stageStatuses = append(stageStatuses, v1alpha1.StageStatus{
+				Name:     stage.Name,
+				Phase:    v1alpha1.StageSkipped,
+				Attempts: attempts[stage.Name],
+			})
+			continue
+		}
 		if !ok {
-			missingCount++
+			// job is not found
+			stageStatuses = append(stageStatuses, v1alpha1.StageStatus{
+				Name:     stage.Name,
+				Phase:    v1alpha1.StageJobMissing,
+				Attempts: attempts[stage.Name],
+			})
 			continue
 		}
 
-		if missingCount != 0 {
-			for k := 0; k < missingCount; k++ {
-				// job is not found
-				stageStatuses = append(stageStatuses, v1alpha1.StageStatus{
-					Phase: v1alpha1.StageJobMissing,
-				})
-			}
-			missingCount = 0
+		stageStatuses = append(stageStatuses, v1alpha1.StageStatus{
+			Name:     stage.Name,
+			Job:      job.Name,
+			Phase:    jobPhase(job),
+			Attempts: attempts[stage.Name],
+		})
+	}
+
+	if err := c.checkRunnableStages(flow, jobMap, stageStatuses); err != nil {
+		return nil, err
+	}
+
+	return stageStatuses, nil
+}
+
+// checkRunnableStages marks runnable stages as failed if their jobs
+// can't be created because of secrets or pod policy
+func (c *Controller) checkRunnableStages(
+	flow *v1alpha1.Flow,
+	jobMap map[string]*batchv1.Job,
+	stageStatuses []v1alpha1.StageStatus,
+) error {
+	graph, err := stages.NewGraph(flow.Spec.Stages)
+	if err != nil {
+		// invalid stages will be reported by stages condition
+		return nil
+	}
+
+	started, completed := stageProgress(flow, jobMap)
+	for _, index := range graph.Runnable(started, completed) {
+		s := &stageStatuses[index]
+		if s.Phase != v1alpha1.StageJobMissing {
+			continue
 		}
 
-		jobPhase := v1alpha1.StageJobRunning
+		if err := c.checkStage(flow, &flow.Spec.Stages[index]); err != nil {
+			e, ok := err.(*stageError)
+			if !ok {
+				return err
+			}
+			s.Phase = v1alpha1.StageJobFailed
+			s.Reason = e.reason
+			s.Message = e.message
+		}
+	}
+	return nil
+}
 
-		if IsJobComplete(job) {
-			jobPhase = v1alpha1.StageJobComplete
+// calculateMatrixStageStatus returns status of stage with matrix,
+// phase of stage is calculated from all of its legs
+func calculateMatrixStageStatus(
+	flow *v1alpha1.Flow,
+	stage *v1alpha1.Stage,
+	jobMap map[string]*batchv1.Job,
+) v1alpha1.StageStatus {
+	legs := []v1alpha1.StageLeg{}
+	phases := []string{}
+	for _, leg := range expandMatrix(stage) {
+		status := v1alpha1.StageLeg{
+			Phase:      v1alpha1.StageJobMissing,
+			Parameters: leg.parameters(),
+		}
+		if job, ok := jobMap[leg.key(stage)]; ok {
+			status.Job = job.Name
+			status.Phase = jobPhase(job)
 		}
+		legs = append(legs, status)
+		phases = append(phases, status.Phase)
+	}
 
-		if IsJobFailed(job) {
-			jobPhase = v1alpha1.StageJobFailed
+	phase := stagePhase(phases)
+	if phase == v1alpha1.StageJobMissing && isStageSkipped(flow, stage) {
+		return v1alpha1.StageStatus{
+			Name:  stage.Name,
+			Phase: v1alpha1.StageSkipped,
 		}
+	}
 
-		stageStatuses = append(stageStatuses, v1alpha1.StageStatus{
-			Job:   job.Name,
-			Phase: jobPhase,
-		})
+	return v1alpha1.StageStatus{
+		Name:  stage.Name,
+		Phase: phase,
+		Legs:  legs,
 	}
-	return stageStatuses, nil
+}
+
+func jobPhase(job *batchv1.Job) string {
+	if IsJobTimedOut(job) {
+		return v1alpha1.StageJobTimedOut
+	}
+
+	if IsJobFailed(job) {
+		return v1alpha1.StageJobFailed
+	}
+
+	if IsJobComplete(job) {
+		return v1alpha1.StageJobComplete
+	}
+
+	return v1alpha1.StageJobRunning
 }
 
 func (c *Controller) generateFlowStatus(flow *v1alpha1.Flow, jobMap map[string]*batchv1.Job, pvc *corev1.PersistentVolumeClaim) (*v1alpha1.FlowStatus, error) {
@@ -91,27 +251,46 @@ func (c *Controller) generateFlowStatus(flow *v1alpha1.Flow, jobMap map[string]*
 	marioCond := generateMarioCondition(flow, gitJob, marioJob)
 	status.Conditions = append(status.Conditions, *marioCond)
 
-	stageStatuses, err := c.calculateStageStatus(flow.Spec.Stages, jobMap)
+	stagesCond := generateStagesCondition(flow)
+	status.Conditions = append(status.Conditions, *stagesCond)
+
+	stageStatuses, err := c.calculateStageStatus(flow, jobMap)
 	if err != nil {
 		return nil, err
 	}
 	status.StageStatuses = stageStatuses
-	length := len(stageStatuses)
 
-	if length == 0 {
-		status.Phase = v1alpha1.FlowPending
+	if cond := generateStageTimedOutCondition(stageStatuses); cond != nil {
+		status.Conditions = append(status.Conditions, *cond)
+	}
+
+	if stagesCond.Status != corev1.ConditionTrue || marioCond.Reason == v1alpha1.FlowReasonInvalidMario {
+		status.Phase = v1alpha1.FlowFailed
 		return &status, nil
 	}
 
-	lastPhase := stageStatuses[length-1].Phase
+	missing, completed, skipped := 0, 0, 0
+	for i := range stageStatuses {
+		switch stageStatuses[i].Phase {
+		case v1alpha1.StageJobFailed, v1alpha1.StageJobTimedOut:
+			status.Phase = v1alpha1.FlowFailed
+			return &status, nil
+		case v1alpha1.StageJobMissing:
+			missing++
+		case v1alpha1.StageJobComplete:
+			completed++
+		case v1alpha1.StageSkipped:
+			skipped++
+		}
+	}
 
-	if lastPhase == v1alpha1.StageJobFailed {
-		status.Phase = v1alpha1.FlowFailed
+	// flow is pending if no job is created
+	if missing == len(stageStatuses) || (missing != 0 && missing+skipped == len(stageStatuses)) {
+		status.Phase = v1alpha1.FlowPending
 		return &status, nil
 	}
 
-	if lastPhase == v1alpha1.StageJobComplete &&
-		len(status.StageStatuses) == len(flow.Spec.Stages) {
+	if completed+skipped == len(stageStatuses) {
 		status.Phase = v1alpha1.FlowSucceed
 		return &status, nil
 	}
@@ -120,6 +299,23 @@ func (c *Controller) generateFlowStatus(flow *v1alpha1.Flow, jobMap map[string]*
 	return &status, nil
 }
 
+func generateStagesCondition(flow *v1alpha1.Flow) *v1alpha1.FlowCondition {
+	if _, err := stages.NewGraph(flow.Spec.Stages); err != nil {
+		return NewFlowCondition(
+			v1alpha1.FlowStagesResolved,
+			corev1.ConditionFalse,
+			v1alpha1.FlowReasonInvalidDependency,
+			err.Error(),
+		)
+	}
+	return NewFlowCondition(
+		v1alpha1.FlowStagesResolved,
+		corev1.ConditionTrue,
+		v1alpha1.FlowReasonStagesResolved,
+		"Dependencies of stages are resolved",
+	)
+}
+
 func generateGitVolumeCondition(pvc *corev1.PersistentVolumeClaim) *v1alpha1.FlowCondition {
 	if pvc == nil {
 		return NewFlowCondition(
@@ -170,6 +366,11 @@ func generateMarioCondition(flow *v1alpha1.Flow, gitJob, marioJob *batchv1.Job)
 		)
 	}
 
+	// invalid mario is kept until mario is attached
+	if isMarioInvalid(flow) {
+		return getFlowCondition(&flow.Status, v1alpha1.FlowMarioReady).DeepCopy()
+	}
+
 	if marioJob != nil {
 		if IsJobFailed(marioJob) {
 			return NewFlowCondition(
@@ -0,0 +1,67 @@
+package flow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	"github.com/liubog2008/oooops/pkg/client/clientset/fake"
+)
+
+func TestFailFlow(t *testing.T) {
+	flow := &v1alpha1.Flow{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "test",
+		},
+		Status: v1alpha1.FlowStatus{
+			Phase: v1alpha1.FlowPending,
+		},
+	}
+	client := fake.NewSimpleClientset(flow)
+	c := &Controller{
+		extClient: client,
+	}
+
+	cond := NewFlowCondition(v1alpha1.FlowInvalid, corev1.ConditionTrue, v1alpha1.FlowReasonInvalidSpec, "invalid")
+	assert.NoError(t, c.failFlow(flow, cond))
+
+	got, err := client.MarioV1alpha1().Flows("default").Get("test", metav1.GetOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, v1alpha1.FlowFailed, got.Status.Phase)
+	assert.NotNil(t, got.Status.CompletionTime)
+	if assert.Len(t, got.Status.Conditions, 1) {
+		assert.Equal(t, v1alpha1.FlowReasonInvalidSpec, got.Status.Conditions[0].Reason)
+	}
+
+	// failed flow with the same condition is not updated again
+	client.ClearActions()
+	assert.NoError(t, c.failFlow(got, cond))
+	assert.Empty(t, client.Actions())
+}
+
+func TestGenerateMarioCondition(t *testing.T) {
+	flow := &v1alpha1.Flow{}
+	marioJob := &batchv1.Job{}
+
+	cond := generateMarioCondition(flow, nil, marioJob)
+	assert.Equal(t, v1alpha1.FlowReasonMarioPending, cond.Reason)
+
+	// invalid mario is kept
+	flow.Status.Conditions = []v1alpha1.FlowCondition{
+		*NewFlowCondition(v1alpha1.FlowMarioReady, corev1.ConditionFalse, v1alpha1.FlowReasonInvalidMario, "invalid"),
+	}
+	cond = generateMarioCondition(flow, nil, marioJob)
+	assert.Equal(t, v1alpha1.FlowReasonInvalidMario, cond.Reason)
+	assert.Equal(t, "invalid", cond.Message)
+
+	flow.Spec.Mario = &v1alpha1.Mario{}
+	cond = generateMarioCondition(flow, nil, marioJob)
+	assert.Equal(t, v1alpha1.FlowReasonMarioReady, cond.Reason)
+}
@@ -0,0 +1,121 @@
+package flow
+
+import (
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+const (
+	// jobReasonDeadlineExceeded is reason of failed job condition when
+	// job exceeds active deadline
+	jobReasonDeadlineExceeded = "DeadlineExceeded"
+)
+
+// flowTimeLeft returns left time of flow before it is timed out,
+// it returns false if timeout of flow is not set.
+// Flow which is not started has the whole timeout left
+func flowTimeLeft(flow *v1alpha1.Flow, now time.Time) (time.Duration, bool) {
+	if flow.Spec.Timeout == nil {
+		return 0, false
+	}
+	if flow.Status.StartTime == nil {
+		return flow.Spec.Timeout.Duration, true
+	}
+	deadline := flow.Status.StartTime.Add(flow.Spec.Timeout.Duration)
+	return deadline.Sub(now), true
+}
+
+// isFlowTimedOut returns true if flow exceeds its timeout
+func isFlowTimedOut(flow *v1alpha1.Flow, now time.Time) bool {
+	left, ok := flowTimeLeft(flow, now)
+	return ok && left <= 0
+}
+
+// activeDeadlineSeconds returns the smaller one of timeout and left time of flow,
+// nil will be returned if neither is set
+func activeDeadlineSeconds(flow *v1alpha1.Flow, timeout *metav1.Duration, now time.Time) *int64 {
+	var deadline *time.Duration
+	if timeout != nil {
+		d := timeout.Duration
+		deadline = &d
+	}
+
+	if left, ok := flowTimeLeft(flow, now); ok {
+		if deadline == nil || left < *deadline {
+			deadline = &left
+		}
+	}
+
+	if deadline == nil {
+		return nil
+	}
+
+	seconds := int64(deadline.Seconds())
+	// active deadline seconds must be positive
+	if seconds < 1 {
+		seconds = 1
+	}
+	return &seconds
+}
+
+// stageTimeout returns timeout of stage, timeout of action will be used
+// if stage timeout is not set
+func stageTimeout(stage *v1alpha1.Stage, action *v1alpha1.MarioAction) *metav1.Duration {
+	if stage.Timeout != nil {
+		return stage.Timeout
+	}
+	return action.Timeout
+}
+
+// IsJobTimedOut returns true if job is failed because of exceeding deadline
+func IsJobTimedOut(job *batchv1.Job) bool {
+	for i := range job.Status.Conditions {
+		c := &job.Status.Conditions[i]
+
+		if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue {
+			return c.Reason == jobReasonDeadlineExceeded
+		}
+	}
+	return false
+}
+
+// timeoutFlowStatus marks unfinished stages as timed out and
+// changes phase of unfinished flow to failed
+func timeoutFlowStatus(status *v1alpha1.FlowStatus) {
+	switch status.Phase {
+	case v1alpha1.FlowSucceed, v1alpha1.FlowFailed:
+		return
+	}
+	status.Phase = v1alpha1.FlowFailed
+
+	markUnfinishedStages(status, v1alpha1.StageJobTimedOut)
+
+	status.Conditions = append(status.Conditions, *NewFlowCondition(
+		v1alpha1.FlowTimedOut,
+		corev1.ConditionTrue,
+		v1alpha1.FlowReasonDeadlineExceeded,
+		"Flow runs longer than its timeout",
+	))
+}
+
+// generateStageTimedOutCondition returns condition if some stages are timed out
+func generateStageTimedOutCondition(stageStatuses []v1alpha1.StageStatus) *v1alpha1.FlowCondition {
+	for i := range stageStatuses {
+		s := &stageStatuses[i]
+		if s.Phase == v1alpha1.StageJobTimedOut {
+			return NewFlowCondition(
+				v1alpha1.FlowTimedOut,
+				corev1.ConditionTrue,
+				v1alpha1.FlowReasonStageDeadlineExceeded,
+				fmt.Sprintf("Stage %s runs longer than its timeout", s.Name),
+			)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package flow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+func TestActiveDeadlineSeconds(t *testing.T) {
+	now := time.Now()
+	started := metav1.NewTime(now.Add(-time.Minute))
+
+	noTimeout := &v1alpha1.Flow{
+		Status: v1alpha1.FlowStatus{StartTime: &started},
+	}
+	withTimeout := &v1alpha1.Flow{
+		Spec: v1alpha1.FlowSpec{
+			Timeout: &metav1.Duration{Duration: 10 * time.Minute},
+		},
+		Status: v1alpha1.FlowStatus{StartTime: &started},
+	}
+	expired := &v1alpha1.Flow{
+		Spec: v1alpha1.FlowSpec{
+			Timeout: &metav1.Duration{Duration: 30 * time.Second},
+		},
+		Status: v1alpha1.FlowStatus{StartTime: &started},
+	}
+
+	assert.Nil(t, activeDeadlineSeconds(noTimeout, nil, now))
+	assert.Equal(t, int64(120), *activeDeadlineSeconds(noTimeout, &metav1.Duration{Duration: 2 * time.Minute}, now))
+	assert.Equal(t, int64(540), *activeDeadlineSeconds(withTimeout, nil, now))
+	assert.Equal(t, int64(120), *activeDeadlineSeconds(withTimeout, &metav1.Duration{Duration: 2 * time.Minute}, now))
+	assert.Equal(t, int64(1), *activeDeadlineSeconds(expired, nil, now))
+
+	assert.False(t, isFlowTimedOut(noTimeout, now))
+	assert.False(t, isFlowTimedOut(withTimeout, now))
+	assert.True(t, isFlowTimedOut(expired, now))
+
+	// time before flow is started is not counted
+	notStarted := expired.DeepCopy()
+	notStarted.Status.StartTime = nil
+	assert.False(t, isFlowTimedOut(notStarted, now))
+	assert.Equal(t, int64(30), *activeDeadlineSeconds(notStarted, nil, now))
+}
+
+func TestTimeoutFlowStatus(t *testing.T) {
+	status := v1alpha1.FlowStatus{
+		Phase: v1alpha1.FlowRunning,
+		StageStatuses: []v1alpha1.StageStatus{
+			{Name: "a", Phase: v1alpha1.StageJobComplete},
+			{Name: "b", Phase: v1alpha1.StageJobRunning},
+			{Name: "c", Phase: v1alpha1.StageJobMissing},
+		},
+	}
+
+	timeoutFlowStatus(&status)
+
+	assert.Equal(t, v1alpha1.FlowFailed, status.Phase)
+	assert.Equal(t, v1alpha1.StageJobComplete, status.StageStatuses[0].Phase)
+	assert.Equal(t, v1alpha1.StageJobTimedOut, status.StageStatuses[1].Phase)
+	assert.Equal(t, v1alpha1.StageJobTimedOut, status.StageStatuses[2].Phase)
+	assert.Equal(t, v1alpha1.FlowTimedOut, status.Conditions[0].Type)
+}
@@ -0,0 +1,96 @@
+package flow
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+const (
+	// marioTokenKey defines key of token in mario token secret
+	marioTokenKey = "token"
+	// marioTokenPath defines path to mount mario token secret
+	marioTokenPath = "/etc/mario-token"
+	// marioTokenVolumeName defines volume name of mario token secret
+	marioTokenVolumeName = "mario-token"
+	// marioTokenBytes defines number of random bytes of mario token
+	marioTokenBytes = 32
+)
+
+// marioTokenSecretName returns name of secret which stores token
+// to fetch mario from mario job of flow
+func marioTokenSecretName(flow *v1alpha1.Flow) string {
+	return flow.Name + "-mario-token"
+}
+
+func (c *Controller) syncTokenSecret(flow *v1alpha1.Flow, secret *corev1.Secret) error {
+	if secret != nil {
+		if !metav1.IsControlledBy(secret, flow) {
+			return fmt.Errorf("can't create secret %s/%s, it exists and is not controlled by flow", secret.Namespace, secret.Name)
+		}
+
+		// token is never changed once it is generated
+		return nil
+	}
+
+	secret, err := c.generateTokenSecret(flow)
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.kubeClient.CoreV1().Secrets(flow.Namespace).Create(secret); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// generateTokenSecret generates secret with a random token of flow
+func (c *Controller) generateTokenSecret(flow *v1alpha1.Flow) (*corev1.Secret, error) {
+	owner := metav1.NewControllerRef(flow, c.GroupVersionKind)
+
+	b := make([]byte, marioTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("can't generate mario token: %v", err)
+	}
+
+	secret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      marioTokenSecretName(flow),
+			Namespace: flow.Namespace,
+			Labels: map[string]string{
+				v1alpha1.DefaultFlowTokenLabelKey: flow.Name,
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				*owner,
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			marioTokenKey: []byte(hex.EncodeToString(b)),
+		},
+	}
+
+	return &secret, nil
+}
+
+// getMarioToken returns token of flow from the secret controlled by flow
+func (c *Controller) getMarioToken(flow *v1alpha1.Flow) (string, error) {
+	secret, err := c.secretLister.Secrets(flow.Namespace).Get(marioTokenSecretName(flow))
+	if err != nil {
+		return "", err
+	}
+	if !metav1.IsControlledBy(secret, flow) {
+		return "", fmt.Errorf("secret %s/%s is not controlled by flow", secret.Namespace, secret.Name)
+	}
+	token, ok := secret.Data[marioTokenKey]
+	if !ok || len(token) == 0 {
+		return "", fmt.Errorf("token is not found in secret %s/%s", secret.Namespace, secret.Name)
+	}
+	return string(token), nil
+}
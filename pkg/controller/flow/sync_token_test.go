@@ -0,0 +1,60 @@
+package flow
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+func TestGenerateTokenSecret(t *testing.T) {
+	c := &Controller{
+		GroupVersionKind: v1alpha1.SchemeGroupVersion.WithKind("Flow"),
+	}
+	flow := &v1alpha1.Flow{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "test",
+		},
+		Spec: v1alpha1.FlowSpec{
+			Selector: &metav1.LabelSelector{},
+			Git: v1alpha1.Git{
+				Repo: "https://github.com/liubog2008/oooops",
+				Ref:  "refs/heads/master",
+			},
+		},
+	}
+
+	secret, err := c.generateTokenSecret(flow)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "test-mario-token", secret.Name)
+	assert.True(t, metav1.IsControlledBy(secret, flow))
+	// only labeled secrets are watched by controller
+	assert.Equal(t, "test", secret.Labels[v1alpha1.DefaultFlowTokenLabelKey])
+
+	token := string(secret.Data[marioTokenKey])
+	assert.Len(t, token, 2*marioTokenBytes)
+
+	other, err := c.generateTokenSecret(flow)
+	assert.NoError(t, err)
+	assert.NotEqual(t, token, string(other.Data[marioTokenKey]), "token should be random")
+
+	job := c.generateMarioJob(flow)
+	spec := &job.Spec.Template.Spec
+	command := strings.Join(spec.Containers[0].Command, " ")
+	assert.NotContains(t, command, token)
+	assert.Contains(t, command, "--token-file "+marioTokenPath+"/"+marioTokenKey)
+
+	found := false
+	for _, v := range spec.Volumes {
+		if v.Secret != nil && v.Secret.SecretName == secret.Name {
+			found = true
+		}
+	}
+	assert.True(t, found, "token secret should be mounted into mario job")
+}
@@ -2,7 +2,9 @@ package pipe
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/liubog2008/oooops/pkg/apis/mario/defaults"
 	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
 	"github.com/liubog2008/oooops/pkg/client/clientset"
 	"github.com/liubog2008/oooops/pkg/client/clientset/scheme"
@@ -31,6 +33,13 @@ type ControllerOptions struct {
 	PipeInformer marioinformers.PipeInformer
 
 	FlowInformer marioinformers.FlowInformer
+
+	// EventTTL defines how long consumed or ignored events are retained,
+	// events will never be deleted if it is zero
+	EventTTL time.Duration
+
+	// Defaulter sets defaults of pipes before they are synced
+	Defaulter defaults.Interface
 }
 
 // Controller defines controller to manage pipe lifecycle and generate flow
@@ -51,6 +60,11 @@ type Controller struct {
 
 	queue workqueue.RateLimitingInterface
 
+	eventQueue workqueue.RateLimitingInterface
+	eventTTL   time.Duration
+
+	defaulter defaults.Interface
+
 	buildReconciler controller.ReconcilerBuilder
 }
 
@@ -75,6 +89,11 @@ func NewController(opt *ControllerOptions) *Controller {
 
 		queue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "pipe"),
 
+		eventQueue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "event"),
+		eventTTL:   opt.EventTTL,
+
+		defaulter: opt.Defaulter,
+
 		pipeLister:  opt.PipeInformer.Lister(),
 		eventLister: opt.EventInformer.Lister(),
 		flowLister:  opt.FlowInformer.Lister(),
@@ -97,6 +116,12 @@ func NewController(opt *ControllerOptions) *Controller {
 		DeleteFunc: c.deleteEvent,
 	})
 
+	opt.FlowInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.addFlow,
+		UpdateFunc: c.updateFlow,
+		DeleteFunc: c.deleteFlow,
+	})
+
 	return c
 }
 
@@ -104,6 +129,7 @@ func NewController(opt *ControllerOptions) *Controller {
 func (c *Controller) Run(workers int, stopCh <-chan struct{}) {
 	defer utilruntime.HandleCrash()
 	defer c.queue.ShutDown()
+	defer c.eventQueue.ShutDown()
 
 	klog.Infof("Starting pipe controller")
 	defer klog.Infof("Shutting down pipe controller")
@@ -117,6 +143,7 @@ func (c *Controller) Run(workers int, stopCh <-chan struct{}) {
 
 	for i := 0; i < workers; i++ {
 		controller.WaitUntil("pipe", c.buildReconciler(c.queue, c.syncPipe), stopCh)
+		controller.WaitUntil("event", c.buildReconciler(c.eventQueue, c.syncEvent), stopCh)
 	}
 
 	klog.Infof("pipe controller is working")
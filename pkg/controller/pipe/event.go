@@ -0,0 +1,152 @@
+package pipe
+
+import (
+	"reflect"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+// findEventPipeStatus returns status of pipe in event status
+func findEventPipeStatus(event *v1alpha1.Event, name string) *v1alpha1.EventPipeStatus {
+	for i := range event.Status.Pipes {
+		s := &event.Status.Pipes[i]
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// isHandled returns true if event is consumed or ignored by the current generation of pipe,
+// failed event will be handled again
+func isHandled(pipe *v1alpha1.Pipe, event *v1alpha1.Event) bool {
+	if isPruned(pipe, event) {
+		return true
+	}
+	s := findEventPipeStatus(event, pipe.Name)
+	return s != nil && s.ObservedGeneration == pipe.Generation && s.Phase != v1alpha1.EventFailed
+}
+
+// isPruned returns true if flow of event has been pruned by pipe
+func isPruned(pipe *v1alpha1.Pipe, event *v1alpha1.Event) bool {
+	s := findEventPipeStatus(event, pipe.Name)
+	return s != nil && s.Reason == v1alpha1.EventReasonFlowPruned
+}
+
+// updateEventPipeStatus records result of pipe in event status
+func (c *Controller) updateEventPipeStatus(event *v1alpha1.Event, status *v1alpha1.EventPipeStatus) error {
+	if s := findEventPipeStatus(event, status.Name); s != nil && reflect.DeepEqual(s, status) {
+		return nil
+	}
+
+	updating := event.DeepCopy()
+	if s := findEventPipeStatus(updating, status.Name); s != nil {
+		*s = *status
+	} else {
+		updating.Status.Pipes = append(updating.Status.Pipes, *status)
+	}
+
+	if _, err := c.extClient.MarioV1alpha1().Events(updating.Namespace).UpdateStatus(updating); err != nil {
+		return err
+	}
+	return nil
+}
+
+// eventPhase returns phase of event from results of pipes which watch it
+func eventPhase(event *v1alpha1.Event, watchers []*v1alpha1.Pipe) string {
+	consumed, failed := false, false
+	for _, pipe := range watchers {
+		s := findEventPipeStatus(event, pipe.Name)
+		if s == nil || (s.ObservedGeneration != pipe.Generation && s.Reason != v1alpha1.EventReasonFlowPruned) {
+			return v1alpha1.EventPending
+		}
+		switch s.Phase {
+		case v1alpha1.EventConsumed:
+			consumed = true
+		case v1alpha1.EventFailed:
+			failed = true
+		}
+	}
+
+	switch {
+	case failed:
+		return v1alpha1.EventFailed
+	case consumed:
+		return v1alpha1.EventConsumed
+	}
+	return v1alpha1.EventIgnored
+}
+
+// syncEvent updates phase of event and deletes event
+// which has been consumed or ignored for longer than ttl
+func (c *Controller) syncEvent(key string) error {
+	startTime := time.Now()
+
+	defer func() {
+		klog.V(4).Infof("Finished syncing event %q. (%v)", key, time.Since(startTime))
+	}()
+
+	ns, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+
+	event, err := c.eventLister.Events(ns).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	watchers, err := c.getPipeWatchers(event)
+	if err != nil {
+		return err
+	}
+
+	status := event.Status.DeepCopy()
+	status.Phase = eventPhase(event, watchers)
+	switch status.Phase {
+	case v1alpha1.EventConsumed, v1alpha1.EventIgnored:
+		if status.CompletionTime == nil {
+			now := metav1.Now()
+			status.CompletionTime = &now
+		}
+	default:
+		status.CompletionTime = nil
+	}
+
+	if !reflect.DeepEqual(status, &event.Status) {
+		updating := event.DeepCopy()
+		updating.Status = *status
+		// event will be synced again after it is updated
+		if _, err := c.extClient.MarioV1alpha1().Events(ns).UpdateStatus(updating); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	if c.eventTTL == 0 || status.CompletionTime == nil {
+		return nil
+	}
+
+	left := status.CompletionTime.Add(c.eventTTL).Sub(time.Now())
+	if left > 0 {
+		c.eventQueue.AddAfter(key, left)
+		return nil
+	}
+
+	klog.Infof("event %s/%s expires, delete it", ns, name)
+
+	if err := c.extClient.MarioV1alpha1().Events(ns).Delete(name, &metav1.DeleteOptions{}); err != nil &&
+		!errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
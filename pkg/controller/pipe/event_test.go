@@ -0,0 +1,83 @@
+package pipe
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+func TestEventPhase(t *testing.T) {
+	ci := &v1alpha1.Pipe{ObjectMeta: metav1.ObjectMeta{Name: "ci", Generation: 2}}
+	release := &v1alpha1.Pipe{ObjectMeta: metav1.ObjectMeta{Name: "release", Generation: 1}}
+
+	cases := []struct {
+		desc     string
+		pipes    []v1alpha1.EventPipeStatus
+		watchers []*v1alpha1.Pipe
+		expected string
+	}{
+		{
+			desc:     "no watcher",
+			expected: v1alpha1.EventIgnored,
+		},
+		{
+			desc:     "not handled",
+			watchers: []*v1alpha1.Pipe{ci},
+			expected: v1alpha1.EventPending,
+		},
+		{
+			desc: "handled by old generation",
+			pipes: []v1alpha1.EventPipeStatus{
+				{Name: "ci", ObservedGeneration: 1, Phase: v1alpha1.EventConsumed, Flow: "ci-a"},
+			},
+			watchers: []*v1alpha1.Pipe{ci},
+			expected: v1alpha1.EventPending,
+		},
+		{
+			desc: "consumed by one and ignored by another",
+			pipes: []v1alpha1.EventPipeStatus{
+				{Name: "ci", ObservedGeneration: 2, Phase: v1alpha1.EventConsumed, Flow: "ci-a"},
+				{Name: "release", ObservedGeneration: 1, Phase: v1alpha1.EventIgnored, Reason: v1alpha1.EventReasonPathNotChanged},
+			},
+			watchers: []*v1alpha1.Pipe{ci, release},
+			expected: v1alpha1.EventConsumed,
+		},
+		{
+			desc: "ignored",
+			pipes: []v1alpha1.EventPipeStatus{
+				{Name: "release", ObservedGeneration: 1, Phase: v1alpha1.EventIgnored, Reason: v1alpha1.EventReasonPathNotChanged},
+			},
+			watchers: []*v1alpha1.Pipe{release},
+			expected: v1alpha1.EventIgnored,
+		},
+		{
+			desc: "failed",
+			pipes: []v1alpha1.EventPipeStatus{
+				{Name: "ci", ObservedGeneration: 2, Phase: v1alpha1.EventConsumed, Flow: "ci-a"},
+				{Name: "release", ObservedGeneration: 1, Phase: v1alpha1.EventFailed, Reason: v1alpha1.EventReasonGenerateFailed},
+			},
+			watchers: []*v1alpha1.Pipe{ci, release},
+			expected: v1alpha1.EventFailed,
+		},
+		{
+			desc: "pruned flow of old generation",
+			pipes: []v1alpha1.EventPipeStatus{
+				{Name: "ci", ObservedGeneration: 1, Phase: v1alpha1.EventConsumed, Flow: "ci-a", Reason: v1alpha1.EventReasonFlowPruned},
+			},
+			watchers: []*v1alpha1.Pipe{ci},
+			expected: v1alpha1.EventConsumed,
+		},
+	}
+
+	for _, c := range cases {
+		event := &v1alpha1.Event{
+			Status: v1alpha1.EventStatus{
+				Pipes: c.pipes,
+			},
+		}
+		assert.Equal(t, c.expected, eventPhase(event, c.watchers), c.desc)
+	}
+}
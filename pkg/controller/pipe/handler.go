@@ -5,6 +5,7 @@ import (
 	"reflect"
 
 	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/tools/cache"
 )
@@ -15,6 +16,8 @@ func (c *Controller) addEvent(obj interface{}) {
 		utilruntime.HandleError(fmt.Errorf("obj is not Event: %v", obj))
 		return
 	}
+	c.enqueueEvent(event)
+
 	pipes, err := c.getPipeWatchers(event)
 	if err != nil {
 		utilruntime.HandleError(fmt.Errorf("can't get pipe watchers of event %s/%s: %v", event.Namespace, event.Name, err))
@@ -37,6 +40,8 @@ func (c *Controller) updateEvent(old, cur interface{}) {
 		return
 	}
 	if reflect.DeepEqual(&oldEvent.Spec, &curEvent.Spec) {
+		// only phase of event may be changed by status
+		c.enqueueEvent(curEvent)
 		return
 	}
 	c.addEvent(curEvent)
@@ -92,3 +97,65 @@ func (c *Controller) deletePipe(obj interface{}) {
 	}
 	c.addPipe(pipe)
 }
+
+// addFlow enqueues pipe which controls the flow,
+// so that status of pipe can be updated
+func (c *Controller) addFlow(obj interface{}) {
+	flow, ok := obj.(*v1alpha1.Flow)
+	if !ok {
+		utilruntime.HandleError(fmt.Errorf("obj is not Flow: %v", obj))
+		return
+	}
+
+	ref := metav1.GetControllerOf(flow)
+	if ref == nil || ref.Kind != c.Kind {
+		return
+	}
+	pipe, err := c.pipeLister.Pipes(flow.Namespace).Get(ref.Name)
+	if err != nil || pipe.UID != ref.UID {
+		return
+	}
+	c.addPipe(pipe)
+}
+
+// updateFlow enqueues pipe which controls the flow when phase or ref of flow is changed,
+// history of pipe will be pruned after flow is finished
+func (c *Controller) updateFlow(old, cur interface{}) {
+	oldFlow, ok1 := old.(*v1alpha1.Flow)
+	curFlow, ok2 := cur.(*v1alpha1.Flow)
+	if !ok1 || !ok2 {
+		utilruntime.HandleError(fmt.Errorf("either old or cur is not Flow: %v, %v", old, cur))
+		return
+	}
+	if oldFlow.Status.Phase == curFlow.Status.Phase && oldFlow.Spec.Git.Ref == curFlow.Spec.Git.Ref {
+		return
+	}
+	c.addFlow(curFlow)
+}
+
+func (c *Controller) deleteFlow(obj interface{}) {
+	if flow, ok := obj.(*v1alpha1.Flow); ok {
+		c.addFlow(flow)
+		return
+	}
+	tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+	if !ok {
+		utilruntime.HandleError(fmt.Errorf("couldn't get object from tombstone %#v", obj))
+		return
+	}
+	flow, ok := tombstone.Obj.(*v1alpha1.Flow)
+	if !ok {
+		utilruntime.HandleError(fmt.Errorf("tombstone contained object that is not a Flow: %#v", tombstone.Obj))
+		return
+	}
+	c.addFlow(flow)
+}
+
+func (c *Controller) enqueueEvent(event *v1alpha1.Event) {
+	key, err := cache.MetaNamespaceKeyFunc(event)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("couldn't get key for event %+v: %v", event, err))
+		return
+	}
+	c.eventQueue.Add(key)
+}
@@ -1,29 +1,208 @@
 package pipe
 
 import (
+	"net/url"
+	"path"
+	"strings"
+
 	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
 	"k8s.io/apimachinery/pkg/labels"
 )
 
+const (
+	branchRefPrefix = "refs/heads/"
+	tagRefPrefix    = "refs/tags/"
+)
+
 func isWatched(pipe *v1alpha1.Pipe, event *v1alpha1.Event) bool {
-	if pipe.Spec.Git.Repo != event.Spec.Repo {
+	if !isSameRepo(pipe.Spec.Git.Repo, event.Spec.Repo) {
 		return false
 	}
 	for _, when := range pipe.Spec.When {
 		if when == event.Spec.When {
+			switch when {
+			case v1alpha1.PullRequest:
+				return isBaseBranchWatched(pipe, event)
+			case v1alpha1.Schedule:
+				// scheduled event is only watched by pipe which generates it
+				return event.Spec.Extra[v1alpha1.EventExtraPipe] == pipe.Name
+			}
+			return isRefWatched(pipe, event.Spec.Ref)
+		}
+	}
+	return false
+}
+
+// isSameRepo returns true if two repo urls point to the same repo,
+// e.g. https://github.com/a/b and git@github.com:a/b.git
+func isSameRepo(a, b string) bool {
+	return normalizeRepo(a) == normalizeRepo(b)
+}
+
+// normalizeRepo returns repo url in form of host/owner/name,
+// scheme, user, port and .git suffix are ignored
+func normalizeRepo(repo string) string {
+	repo = strings.ToLower(strings.TrimSpace(repo))
+
+	var host, p string
+	if strings.Contains(repo, "://") {
+		u, err := url.Parse(repo)
+		if err != nil {
+			return repo
+		}
+		host, p = u.Hostname(), u.Path
+	} else if i := strings.Index(repo, ":"); i != -1 {
+		// scp-like syntax, e.g. git@github.com:a/b.git
+		host, p = repo[:i], repo[i+1:]
+		if j := strings.LastIndex(host, "@"); j != -1 {
+			host = host[j+1:]
+		}
+	} else {
+		return repo
+	}
+
+	p = strings.TrimSuffix(strings.Trim(p, "/"), ".git")
+	return host + "/" + p
+}
+
+// isBaseBranchWatched returns true if base branch of pull request event
+// matches one of branch patterns of pipe
+func isBaseBranchWatched(pipe *v1alpha1.Pipe, event *v1alpha1.Event) bool {
+	opts := pipe.Spec.PullRequest
+	if opts == nil || len(opts.Branches) == 0 {
+		return true
+	}
+	base := strings.TrimPrefix(event.Spec.Extra[v1alpha1.EventExtraBaseRef], branchRefPrefix)
+	return matchAny(opts.Branches, base)
+}
+
+// isForkAllowed returns false if event is a pull request from a fork
+// and pipe doesn't allow forks explicitly, pull request whose head repo
+// is unknown is treated as from a fork
+func isForkAllowed(pipe *v1alpha1.Pipe, event *v1alpha1.Event) bool {
+	if event.Spec.When != v1alpha1.PullRequest {
+		return true
+	}
+	if opts := pipe.Spec.PullRequest; opts != nil && opts.AllowForks {
+		return true
+	}
+	headRepo := event.Spec.Extra[v1alpha1.EventExtraHeadRepo]
+	return headRepo != "" && isSameRepo(headRepo, event.Spec.Repo)
+}
+
+// isRefWatched returns true if pushed branch or tag matches filters of pipe
+func isRefWatched(pipe *v1alpha1.Pipe, ref string) bool {
+	switch {
+	case strings.HasPrefix(ref, branchRefPrefix):
+		return matchFilter(pipe.Spec.Branches, strings.TrimPrefix(ref, branchRefPrefix))
+	case strings.HasPrefix(ref, tagRefPrefix):
+		return matchFilter(pipe.Spec.Tags, strings.TrimPrefix(ref, tagRefPrefix))
+	}
+	return true
+}
+
+// matchFilter returns true if name matches one of include patterns
+// and none of exclude patterns
+func matchFilter(filter *v1alpha1.PatternFilter, name string) bool {
+	if filter == nil {
+		return true
+	}
+	if len(filter.Include) != 0 && !matchAny(filter.Include, name) {
+		return false
+	}
+	return !matchAny(filter.Exclude, name)
+}
+
+// isPathChanged returns true if some changed files of event match path filter of pipe,
+// event without changed files is always treated as changed
+func isPathChanged(pipe *v1alpha1.Pipe, event *v1alpha1.Event) bool {
+	filter := pipe.Spec.Paths
+	if filter == nil || len(event.Spec.ChangedFiles) == 0 {
+		return true
+	}
+	for _, file := range event.Spec.ChangedFiles {
+		if len(filter.Include) != 0 && !matchPath(filter.Include, file) {
+			continue
+		}
+		if !matchPath(filter.Exclude, file) {
 			return true
 		}
 	}
 	return false
 }
 
+// matchPath returns true if file or one of its parent directories
+// matches one of patterns, pattern without slash also matches base name
+func matchPath(patterns []string, file string) bool {
+	file = path.Clean(file)
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, "/") {
+			if ok, err := path.Match(pattern, path.Base(file)); err == nil && ok {
+				return true
+			}
+		}
+	}
+	for p := file; p != "." && p != "/"; p = path.Dir(p) {
+		if matchAny(patterns, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// concurrencyGroup returns concurrency group of flow generated by event,
+// empty string will be returned if concurrent flows are allowed
+func concurrencyGroup(pipe *v1alpha1.Pipe, event *v1alpha1.Event) string {
+	switch pipe.Spec.ConcurrencyPolicy {
+	case v1alpha1.ForbidConcurrent, v1alpha1.ReplaceConcurrent:
+	default:
+		return ""
+	}
+	if pipe.Spec.ConcurrencyGroup != "" {
+		return pipe.Spec.ConcurrencyGroup
+	}
+	return pipe.Name + "@" + event.Spec.Ref
+}
+
+// matchAny returns true if name matches one of patterns,
+// invalid patterns never match
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, name); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeBaseRef returns base ref which pull request will be merged into,
+// empty string will be returned if head of pull request should be checked out
+func mergeBaseRef(pipe *v1alpha1.Pipe, event *v1alpha1.Event) string {
+	if event.Spec.When != v1alpha1.PullRequest {
+		return ""
+	}
+	opts := pipe.Spec.PullRequest
+	if opts == nil || opts.Checkout != v1alpha1.PullRequestCheckoutMerge {
+		return ""
+	}
+	return event.Spec.Extra[v1alpha1.EventExtraBaseRef]
+}
+
+// defaultPipe returns a copy of pipe with defaults
+func (c *Controller) defaultPipe(pipe *v1alpha1.Pipe) *v1alpha1.Pipe {
+	defaulted := pipe.DeepCopy()
+	c.defaulter.DefaultPipe(defaulted)
+	return defaulted
+}
+
 func (c *Controller) getPipeWatchers(event *v1alpha1.Event) ([]*v1alpha1.Pipe, error) {
 	pipes, err := c.pipeLister.Pipes(event.Namespace).List(labels.Everything())
 	if err != nil {
 		return nil, err
 	}
 	watchers := []*v1alpha1.Pipe{}
-	for _, watcher := range pipes {
+	for _, pipe := range pipes {
+		watcher := c.defaultPipe(pipe)
 		if isWatched(watcher, event) {
 			watchers = append(watchers, watcher)
 		}
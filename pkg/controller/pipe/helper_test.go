@@ -0,0 +1,165 @@
+package pipe
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+func TestIsWatched(t *testing.T) {
+	repo := "https://github.com/liubog2008/oooops"
+
+	ci := &v1alpha1.Pipe{
+		ObjectMeta: metav1.ObjectMeta{Name: "ci"},
+		Spec: v1alpha1.PipeSpec{
+			Git:  v1alpha1.Git{Repo: repo},
+			When: []v1alpha1.When{v1alpha1.Push},
+			Branches: &v1alpha1.PatternFilter{
+				Exclude: []string{"release/*"},
+			},
+		},
+	}
+	release := &v1alpha1.Pipe{
+		ObjectMeta: metav1.ObjectMeta{Name: "release"},
+		Spec: v1alpha1.PipeSpec{
+			Git:  v1alpha1.Git{Repo: repo},
+			When: []v1alpha1.When{v1alpha1.Push, v1alpha1.Tag},
+			Branches: &v1alpha1.PatternFilter{
+				Include: []string{"release/*"},
+			},
+			Tags: &v1alpha1.PatternFilter{
+				Include: []string{"v*"},
+				Exclude: []string{"*-rc*"},
+			},
+		},
+	}
+
+	cases := []struct {
+		when    v1alpha1.When
+		ref     string
+		ci      bool
+		release bool
+	}{
+		{v1alpha1.Push, "refs/heads/master", true, false},
+		{v1alpha1.Push, "refs/heads/feature/a", true, false},
+		{v1alpha1.Push, "refs/heads/release/1.0", false, true},
+		{v1alpha1.Tag, "refs/tags/v1.0.0", false, true},
+		{v1alpha1.Tag, "refs/tags/v1.0.0-rc1", false, false},
+		{v1alpha1.Tag, "refs/tags/nightly", false, false},
+	}
+
+	for _, c := range cases {
+		event := &v1alpha1.Event{
+			Spec: v1alpha1.EventSpec{
+				Repo: repo,
+				When: c.when,
+				Ref:  c.ref,
+			},
+		}
+		assert.Equal(t, c.ci, isWatched(ci, event), "ci: %s", c.ref)
+		assert.Equal(t, c.release, isWatched(release, event), "release: %s", c.ref)
+	}
+}
+
+func TestIsSameRepo(t *testing.T) {
+	repo := "https://github.com/liubog2008/oooops"
+	for _, other := range []string{
+		"https://github.com/liubog2008/oooops",
+		"https://github.com/liubog2008/oooops.git",
+		"https://github.com/liubog2008/oooops/",
+		"https://user@github.com:443/LiuBog2008/oooops.git",
+		"ssh://git@github.com/liubog2008/oooops.git",
+		"git@github.com:liubog2008/oooops.git",
+		"github.com:liubog2008/oooops",
+	} {
+		assert.True(t, isSameRepo(repo, other), other)
+	}
+	for _, other := range []string{
+		"https://github.com/liubog2008/mario",
+		"https://gitlab.com/liubog2008/oooops",
+		"git@github.com:other/oooops.git",
+	} {
+		assert.False(t, isSameRepo(repo, other), other)
+	}
+}
+
+func TestIsForkAllowed(t *testing.T) {
+	repo := "https://github.com/liubog2008/oooops"
+	pipe := &v1alpha1.Pipe{
+		Spec: v1alpha1.PipeSpec{
+			Git: v1alpha1.Git{Repo: repo},
+		},
+	}
+
+	cases := []struct {
+		when     v1alpha1.When
+		headRepo string
+		allowed  bool
+	}{
+		{v1alpha1.Push, "", true},
+		{v1alpha1.PullRequest, repo, true},
+		{v1alpha1.PullRequest, "https://github.com/octocat/oooops", false},
+		{v1alpha1.PullRequest, "", false},
+	}
+
+	for _, c := range cases {
+		event := &v1alpha1.Event{
+			Spec: v1alpha1.EventSpec{
+				Repo: repo,
+				When: c.when,
+				Extra: map[string]string{
+					v1alpha1.EventExtraHeadRepo: c.headRepo,
+				},
+			},
+		}
+		assert.Equal(t, c.allowed, isForkAllowed(pipe, event), "%s: %s", c.when, c.headRepo)
+	}
+
+	pipe.Spec.PullRequest = &v1alpha1.PullRequestOptions{AllowForks: true}
+	assert.True(t, isForkAllowed(pipe, &v1alpha1.Event{
+		Spec: v1alpha1.EventSpec{
+			Repo: repo,
+			When: v1alpha1.PullRequest,
+			Extra: map[string]string{
+				v1alpha1.EventExtraHeadRepo: "https://github.com/octocat/oooops",
+			},
+		},
+	}))
+}
+
+func TestIsPathChanged(t *testing.T) {
+	pipe := &v1alpha1.Pipe{
+		Spec: v1alpha1.PipeSpec{
+			Paths: &v1alpha1.PatternFilter{
+				Include: []string{"services/api", "pkg/*/api.go"},
+				Exclude: []string{"*.md"},
+			},
+		},
+	}
+
+	cases := []struct {
+		files    []string
+		expected bool
+	}{
+		{nil, true},
+		{[]string{"services/api/main.go"}, true},
+		{[]string{"services/api/cmd/main.go"}, true},
+		{[]string{"pkg/foo/api.go"}, true},
+		{[]string{"services/web/main.go"}, false},
+		{[]string{"services/api/README.md"}, false},
+		{[]string{"services/api/README.md", "services/api/main.go"}, true},
+		{[]string{"services/apiserver/main.go"}, false},
+	}
+
+	for _, c := range cases {
+		event := &v1alpha1.Event{
+			Spec: v1alpha1.EventSpec{
+				ChangedFiles: c.files,
+			},
+		}
+		assert.Equal(t, c.expected, isPathChanged(pipe, event), "%v", c.files)
+	}
+}
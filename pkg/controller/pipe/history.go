@@ -0,0 +1,135 @@
+package pipe
+
+import (
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+// finishedTime returns completion time of flow,
+// creation time is used if completion time is not recorded
+func finishedTime(flow *v1alpha1.Flow) time.Time {
+	if flow.Status.CompletionTime != nil {
+		return flow.Status.CompletionTime.Time
+	}
+	return flow.CreationTimestamp.Time
+}
+
+// flowsToPrune returns flows which exceed history limit or ttl and
+// duration until the next flow exceeds ttl, zero duration means no flow will expire
+func flowsToPrune(spec *v1alpha1.PipeSpec, flows []*v1alpha1.Flow, now time.Time) ([]*v1alpha1.Flow, time.Duration) {
+	succeeded := []*v1alpha1.Flow{}
+	failed := []*v1alpha1.Flow{}
+	for _, flow := range flows {
+		switch flow.Status.Phase {
+		case v1alpha1.FlowSucceed:
+			succeeded = append(succeeded, flow)
+		case v1alpha1.FlowFailed, v1alpha1.FlowCancelled:
+			failed = append(failed, flow)
+		}
+	}
+
+	pruned := []*v1alpha1.Flow{}
+	var expiring time.Duration
+
+	for _, history := range []struct {
+		flows []*v1alpha1.Flow
+		limit *int32
+	}{
+		{succeeded, spec.SuccessfulFlowsHistoryLimit},
+		{failed, spec.FailedFlowsHistoryLimit},
+	} {
+		// newest first
+		sort.Slice(history.flows, func(i, j int) bool {
+			return finishedTime(history.flows[i]).After(finishedTime(history.flows[j]))
+		})
+
+		for i, flow := range history.flows {
+			if history.limit != nil && i >= int(*history.limit) {
+				pruned = append(pruned, flow)
+				continue
+			}
+			if spec.FlowTTLSecondsAfterFinished == nil {
+				continue
+			}
+			ttl := time.Duration(*spec.FlowTTLSecondsAfterFinished) * time.Second
+			left := finishedTime(flow).Add(ttl).Sub(now)
+			if left <= 0 {
+				pruned = append(pruned, flow)
+				continue
+			}
+			if expiring == 0 || left < expiring {
+				expiring = left
+			}
+		}
+	}
+
+	return pruned, expiring
+}
+
+// syncHistory deletes finished flows of pipe which exceed history limits or ttl,
+// jobs, config maps and volumes of flows are deleted by garbage collector
+func (c *Controller) syncHistory(key string, pipe *v1alpha1.Pipe, now time.Time) error {
+	spec := &pipe.Spec
+	if spec.SuccessfulFlowsHistoryLimit == nil && spec.FailedFlowsHistoryLimit == nil &&
+		spec.FlowTTLSecondsAfterFinished == nil {
+		return nil
+	}
+
+	flows, err := c.listFlows(pipe)
+	if err != nil {
+		return err
+	}
+
+	pruned, expiring := flowsToPrune(spec, flows, now)
+	if expiring != 0 {
+		c.queue.AddAfter(key, expiring)
+	}
+
+	policy := metav1.DeletePropagationBackground
+	for _, flow := range pruned {
+		// event should not generate the flow again
+		if err := c.markEventsPruned(pipe, flow); err != nil {
+			return err
+		}
+
+		klog.Infof("prune flow %s/%s of pipe %s", flow.Namespace, flow.Name, pipe.Name)
+
+		if err := c.extClient.MarioV1alpha1().Flows(flow.Namespace).Delete(flow.Name, &metav1.DeleteOptions{
+			PropagationPolicy: &policy,
+		}); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// markEventsPruned records in status of events that flow of pipe has been pruned
+func (c *Controller) markEventsPruned(pipe *v1alpha1.Pipe, flow *v1alpha1.Flow) error {
+	events, err := c.eventLister.Events(flow.Namespace).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		s := findEventPipeStatus(event, pipe.Name)
+		if s == nil || s.Flow != flow.Name || s.Reason == v1alpha1.EventReasonFlowPruned {
+			continue
+		}
+
+		pruned := *s
+		pruned.Reason = v1alpha1.EventReasonFlowPruned
+		pruned.Message = "Flow is pruned by history limits or ttl of pipe"
+		if err := c.updateEventPipeStatus(event, &pruned); err != nil {
+			return err
+		}
+	}
+	return nil
+}
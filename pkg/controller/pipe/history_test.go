@@ -0,0 +1,69 @@
+package pipe
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+func TestFlowsToPrune(t *testing.T) {
+	now := time.Date(2020, 4, 25, 10, 0, 0, 0, time.UTC)
+
+	newFlow := func(name, phase string, finished time.Duration) *v1alpha1.Flow {
+		completion := metav1.NewTime(now.Add(-finished))
+		return &v1alpha1.Flow{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+			Status: v1alpha1.FlowStatus{
+				Phase:          phase,
+				CompletionTime: &completion,
+			},
+		}
+	}
+	int32Ptr := func(i int32) *int32 {
+		return &i
+	}
+
+	flows := []*v1alpha1.Flow{
+		newFlow("s1", v1alpha1.FlowSucceed, 3*time.Hour),
+		newFlow("s2", v1alpha1.FlowSucceed, 1*time.Hour),
+		newFlow("s3", v1alpha1.FlowSucceed, 2*time.Hour),
+		newFlow("f1", v1alpha1.FlowFailed, 3*time.Hour),
+		newFlow("c1", v1alpha1.FlowCancelled, 1*time.Hour),
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "r1"},
+			Status:     v1alpha1.FlowStatus{Phase: v1alpha1.FlowRunning},
+		},
+	}
+
+	names := func(flows []*v1alpha1.Flow) []string {
+		s := []string{}
+		for _, flow := range flows {
+			s = append(s, flow.Name)
+		}
+		return s
+	}
+
+	pruned, expiring := flowsToPrune(&v1alpha1.PipeSpec{}, flows, now)
+	assert.Empty(t, pruned)
+	assert.Zero(t, expiring)
+
+	pruned, expiring = flowsToPrune(&v1alpha1.PipeSpec{
+		SuccessfulFlowsHistoryLimit: int32Ptr(1),
+		FailedFlowsHistoryLimit:     int32Ptr(1),
+	}, flows, now)
+	assert.ElementsMatch(t, []string{"s1", "s3", "f1"}, names(pruned))
+	assert.Zero(t, expiring)
+
+	pruned, expiring = flowsToPrune(&v1alpha1.PipeSpec{
+		SuccessfulFlowsHistoryLimit: int32Ptr(2),
+		FlowTTLSecondsAfterFinished: int32Ptr(int32((150 * time.Minute).Seconds())),
+	}, flows, now)
+	assert.ElementsMatch(t, []string{"s1", "f1"}, names(pruned))
+	assert.Equal(t, 30*time.Minute, expiring)
+}
@@ -0,0 +1,165 @@
+package pipe
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	"github.com/liubog2008/oooops/pkg/utils/cron"
+)
+
+const (
+	// maxMissedSchedules defines max number of missed schedules which will be checked,
+	// all missed schedules will be skipped if there are more than it
+	maxMissedSchedules = 100
+
+	reasonInvalidSchedule        = "InvalidSchedule"
+	reasonMissSchedule           = "MissSchedule"
+	reasonTooManyMissedSchedules = "TooManyMissedSchedules"
+)
+
+// isScheduled returns true if pipe is triggered by schedule
+func isScheduled(pipe *v1alpha1.Pipe) bool {
+	if pipe.Spec.Schedule == nil {
+		return false
+	}
+	for _, when := range pipe.Spec.When {
+		if when == v1alpha1.Schedule {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSchedule returns cron schedule and location of pipe schedule
+func parseSchedule(s *v1alpha1.PipeSchedule) (*cron.Schedule, *time.Location, error) {
+	if s.Ref == "" {
+		return nil, nil, fmt.Errorf("ref of schedule is required")
+	}
+	sched, err := cron.Parse(s.Cron)
+	if err != nil {
+		return nil, nil, err
+	}
+	loc := time.UTC
+	if s.TimeZone != "" {
+		if loc, err = time.LoadLocation(s.TimeZone); err != nil {
+			return nil, nil, fmt.Errorf("can't load time zone %s: %v", s.TimeZone, err)
+		}
+	}
+	return sched, loc, nil
+}
+
+// scheduledTimes returns the latest scheduled time in (earliest, now] and
+// the next scheduled time after now. Zero time will be returned if not found.
+// It returns false if there are too many missed schedules
+func scheduledTimes(sched *cron.Schedule, earliest, now time.Time) (last, next time.Time, ok bool) {
+	missed := 0
+	for t := sched.Next(earliest); !t.IsZero(); t = sched.Next(t) {
+		if t.After(now) {
+			return last, t, true
+		}
+		last = t
+		missed++
+		if missed > maxMissedSchedules {
+			return time.Time{}, sched.Next(now), false
+		}
+	}
+	return last, time.Time{}, true
+}
+
+// syncSchedule generates event of pipe at scheduled time and records it in status,
+// the pipe will be requeued at the next scheduled time
+func (c *Controller) syncSchedule(key string, pipe *v1alpha1.Pipe, status *v1alpha1.PipeStatus, now time.Time) error {
+	if !isScheduled(pipe) {
+		return nil
+	}
+	schedule := pipe.Spec.Schedule
+
+	sched, loc, err := parseSchedule(schedule)
+	if err != nil {
+		c.eventRecorder.Eventf(pipe, corev1.EventTypeWarning, reasonInvalidSchedule, "Schedule of pipe is invalid: %v", err)
+		return nil
+	}
+
+	earliest := pipe.CreationTimestamp.Time
+	if pipe.Status.LastScheduleTime != nil {
+		earliest = pipe.Status.LastScheduleTime.Time
+	}
+	if schedule.StartingDeadlineSeconds != nil {
+		// schedules before deadline will never be started
+		deadline := now.Add(-time.Duration(*schedule.StartingDeadlineSeconds) * time.Second)
+		if deadline.After(earliest) {
+			earliest = deadline
+		}
+	}
+
+	last, next, ok := scheduledTimes(sched, earliest.In(loc), now.In(loc))
+	if !next.IsZero() {
+		c.queue.AddAfter(key, next.Sub(now))
+	}
+
+	if !ok {
+		c.eventRecorder.Eventf(pipe, corev1.EventTypeWarning, reasonTooManyMissedSchedules,
+			"Too many missed schedules (> %d), all of them are skipped, set startingDeadlineSeconds to avoid it",
+			maxMissedSchedules)
+		status.LastScheduleTime = &metav1.Time{Time: now}
+		return nil
+	}
+	if last.IsZero() {
+		return nil
+	}
+
+	if schedule.StartingDeadlineSeconds != nil &&
+		now.Sub(last) > time.Duration(*schedule.StartingDeadlineSeconds)*time.Second {
+		c.eventRecorder.Eventf(pipe, corev1.EventTypeWarning, reasonMissSchedule,
+			"Schedule at %v is missed and exceeds starting deadline", last)
+		status.LastScheduleTime = &metav1.Time{Time: last}
+		return nil
+	}
+
+	if err := c.createScheduledEvent(pipe, last); err != nil {
+		return err
+	}
+
+	status.LastScheduleTime = &metav1.Time{Time: last}
+	return nil
+}
+
+// createScheduledEvent creates event of pipe at scheduled time,
+// it is idempotent because name of event is generated from scheduled time
+func (c *Controller) createScheduledEvent(pipe *v1alpha1.Pipe, scheduled time.Time) error {
+	event := v1alpha1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pipe.Name + "-" + strconv.FormatInt(scheduled.Unix()/60, 10),
+			Namespace: pipe.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(pipe, c.GroupVersionKind),
+			},
+		},
+		Spec: v1alpha1.EventSpec{
+			Repo: pipe.Spec.Git.Repo,
+			When: v1alpha1.Schedule,
+			Ref:  pipe.Spec.Schedule.Ref,
+			Extra: map[string]string{
+				v1alpha1.EventExtraPipe:          pipe.Name,
+				v1alpha1.EventExtraScheduledTime: scheduled.UTC().Format(time.RFC3339),
+			},
+		},
+	}
+
+	if _, err := c.extClient.MarioV1alpha1().Events(pipe.Namespace).Create(&event); err != nil {
+		if errors.IsAlreadyExists(err) {
+			return nil
+		}
+		return err
+	}
+
+	klog.Infof("create scheduled event %s/%s at %v", event.Namespace, event.Name, scheduled)
+	return nil
+}
@@ -0,0 +1,71 @@
+package pipe
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/liubog2008/oooops/pkg/utils/cron"
+)
+
+func TestScheduledTimes(t *testing.T) {
+	hourly, err := cron.Parse("@hourly")
+	assert.NoError(t, err)
+	minutely, err := cron.Parse("* * * * *")
+	assert.NoError(t, err)
+
+	base := time.Date(2020, 4, 25, 10, 30, 0, 0, time.UTC)
+
+	cases := []struct {
+		desc     string
+		sched    *cron.Schedule
+		earliest time.Time
+		now      time.Time
+		last     time.Time
+		next     time.Time
+		ok       bool
+	}{
+		{
+			desc:     "not scheduled yet",
+			sched:    hourly,
+			earliest: base,
+			now:      base.Add(10 * time.Minute),
+			next:     base.Add(30 * time.Minute),
+			ok:       true,
+		},
+		{
+			desc:     "scheduled",
+			sched:    hourly,
+			earliest: base,
+			now:      base.Add(30 * time.Minute),
+			last:     base.Add(30 * time.Minute),
+			next:     base.Add(90 * time.Minute),
+			ok:       true,
+		},
+		{
+			desc:     "only latest missed schedule is returned",
+			sched:    hourly,
+			earliest: base,
+			now:      base.Add(5 * time.Hour),
+			last:     base.Add(270 * time.Minute),
+			next:     base.Add(330 * time.Minute),
+			ok:       true,
+		},
+		{
+			desc:     "too many missed schedules",
+			sched:    minutely,
+			earliest: base,
+			now:      base.Add(24 * time.Hour),
+			next:     base.Add(24*time.Hour + time.Minute),
+			ok:       false,
+		},
+	}
+
+	for _, c := range cases {
+		last, next, ok := scheduledTimes(c.sched, c.earliest, c.now)
+		assert.Equal(t, c.ok, ok, c.desc)
+		assert.True(t, c.last.Equal(last), "%s: expected last %v, got %v", c.desc, c.last, last)
+		assert.True(t, c.next.Equal(next), "%s: expected next %v, got %v", c.desc, c.next, next)
+	}
+}
@@ -0,0 +1,181 @@
+package pipe
+
+import (
+	"fmt"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	"github.com/liubog2008/oooops/pkg/apis/mario/validation"
+)
+
+// isFinishedPhase returns true if phase of flow is succeeded, failed or cancelled
+func isFinishedPhase(phase string) bool {
+	switch phase {
+	case v1alpha1.FlowSucceed, v1alpha1.FlowFailed, v1alpha1.FlowCancelled:
+		return true
+	}
+	return false
+}
+
+// listFlows returns flows controlled by pipe which are not being deleted
+func (c *Controller) listFlows(pipe *v1alpha1.Pipe) ([]*v1alpha1.Flow, error) {
+	all, err := c.flowLister.Flows(pipe.Namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	flows := []*v1alpha1.Flow{}
+	for _, flow := range all {
+		if flow.DeletionTimestamp != nil || !metav1.IsControlledBy(flow, pipe) {
+			continue
+		}
+		flows = append(flows, flow)
+	}
+	return flows, nil
+}
+
+// calculateFlowStatus updates last flow, last successful ref and
+// number of active flows in status, last successful ref is kept
+// if the succeeded flow has been pruned
+func calculateFlowStatus(status *v1alpha1.PipeStatus, flows []*v1alpha1.Flow) {
+	var last, lastSucceeded *v1alpha1.Flow
+	active := int32(0)
+	for _, flow := range flows {
+		if !isFinishedPhase(flow.Status.Phase) {
+			active++
+		}
+		if last == nil || isNewer(flow, last) {
+			last = flow
+		}
+		if flow.Status.Phase == v1alpha1.FlowSucceed &&
+			(lastSucceeded == nil || finishedTime(flow).After(finishedTime(lastSucceeded))) {
+			lastSucceeded = flow
+		}
+	}
+
+	status.ActiveFlows = active
+	if last != nil {
+		status.LastFlow = &v1alpha1.PipeFlowStatus{
+			Name:  last.Name,
+			Phase: last.Status.Phase,
+			Ref:   last.Spec.Git.Ref,
+		}
+	}
+	if lastSucceeded != nil {
+		status.LastSuccessfulRef = lastSucceeded.Spec.Git.Ref
+	}
+}
+
+// isNewer returns true if a is created after b,
+// name is compared if they are created at the same time
+func isNewer(a, b *v1alpha1.Flow) bool {
+	if a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.Name > b.Name
+	}
+	return b.CreationTimestamp.Before(&a.CreationTimestamp)
+}
+
+// setPipeCondition sets condition in status,
+// transition time is kept if status of condition is not changed
+func setPipeCondition(status *v1alpha1.PipeStatus, cond *v1alpha1.PipeCondition) {
+	for i := range status.Conditions {
+		c := &status.Conditions[i]
+		if c.Type != cond.Type {
+			continue
+		}
+		if c.Status == cond.Status {
+			cond.LastTransitionTime = c.LastTransitionTime
+		}
+		*c = *cond
+		return
+	}
+	status.Conditions = append(status.Conditions, *cond)
+}
+
+// newPipeCondition returns a condition of pipe
+func newPipeCondition(t v1alpha1.PipeConditionType, status corev1.ConditionStatus, reason, message string) *v1alpha1.PipeCondition {
+	return &v1alpha1.PipeCondition{
+		Type:               t,
+		Status:             status,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	}
+}
+
+// readyCondition returns ready condition of pipe
+func readyCondition(pipe *v1alpha1.Pipe) *v1alpha1.PipeCondition {
+	if errs := validation.ValidatePipe(pipe); len(errs) != 0 {
+		return newPipeCondition(v1alpha1.PipeReady, corev1.ConditionFalse, v1alpha1.PipeReasonInvalid,
+			fmt.Sprintf("Pipe is invalid: %v", errs.ToAggregate()))
+	}
+	return newPipeCondition(v1alpha1.PipeReady, corev1.ConditionTrue, v1alpha1.PipeReasonValid, "")
+}
+
+// isReady returns true if ready condition in status is true
+func isReady(status *v1alpha1.PipeStatus) bool {
+	for i := range status.Conditions {
+		c := &status.Conditions[i]
+		if c.Type == v1alpha1.PipeReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// triggerCondition returns trigger error condition from error of generating flows
+func triggerCondition(err error) *v1alpha1.PipeCondition {
+	if err != nil {
+		return newPipeCondition(v1alpha1.PipeTriggerError, corev1.ConditionTrue, v1alpha1.PipeReasonGenerateFailed, err.Error())
+	}
+	return newPipeCondition(v1alpha1.PipeTriggerError, corev1.ConditionFalse, v1alpha1.PipeReasonTriggered, "")
+}
+
+// pipePhase returns phase of pipe from its status
+func pipePhase(status *v1alpha1.PipeStatus) string {
+	for i := range status.Conditions {
+		c := &status.Conditions[i]
+		if (c.Type == v1alpha1.PipeReady && c.Status != corev1.ConditionTrue) ||
+			(c.Type == v1alpha1.PipeTriggerError && c.Status == corev1.ConditionTrue) {
+			return v1alpha1.PipeError
+		}
+	}
+	if status.ActiveFlows != 0 {
+		return v1alpha1.PipeRunning
+	}
+	return v1alpha1.PipeIdle
+}
+
+// syncPipeStatus publishes status of pipe and its flows,
+// ready and trigger error conditions should have been set in status
+func (c *Controller) syncPipeStatus(pipe *v1alpha1.Pipe, status *v1alpha1.PipeStatus) error {
+	flows, err := c.listFlows(pipe)
+	if err != nil {
+		return err
+	}
+
+	calculateFlowStatus(status, flows)
+	status.Phase = pipePhase(status)
+
+	if reflect.DeepEqual(status, &pipe.Status) {
+		return nil
+	}
+
+	updating := v1alpha1.Pipe{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       pipe.Namespace,
+			Name:            pipe.Name,
+			ResourceVersion: pipe.ResourceVersion,
+		},
+		Status: *status,
+	}
+
+	if _, err := c.extClient.MarioV1alpha1().Pipes(updating.Namespace).UpdateStatus(&updating); err != nil {
+		return err
+	}
+	return nil
+}
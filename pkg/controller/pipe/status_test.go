@@ -0,0 +1,81 @@
+package pipe
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+func TestCalculateFlowStatus(t *testing.T) {
+	now := time.Date(2020, 4, 25, 10, 0, 0, 0, time.UTC)
+
+	newFlow := func(name, phase, ref string, created time.Duration) *v1alpha1.Flow {
+		flow := &v1alpha1.Flow{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				CreationTimestamp: metav1.NewTime(now.Add(-created)),
+			},
+			Spec: v1alpha1.FlowSpec{
+				Git: v1alpha1.Git{Ref: ref},
+			},
+			Status: v1alpha1.FlowStatus{
+				Phase: phase,
+			},
+		}
+		if isFinishedPhase(phase) {
+			completion := metav1.NewTime(now.Add(-created / 2))
+			flow.Status.CompletionTime = &completion
+		}
+		return flow
+	}
+
+	status := &v1alpha1.PipeStatus{
+		LastSuccessfulRef: "v0.9.0",
+	}
+	calculateFlowStatus(status, nil)
+	assert.Equal(t, &v1alpha1.PipeStatus{
+		LastSuccessfulRef: "v0.9.0",
+	}, status)
+
+	calculateFlowStatus(status, []*v1alpha1.Flow{
+		newFlow("a", v1alpha1.FlowSucceed, "v1.0.0", 4*time.Hour),
+		newFlow("b", v1alpha1.FlowFailed, "v1.1.0", 3*time.Hour),
+		newFlow("c", v1alpha1.FlowRunning, "v1.2.0", 2*time.Hour),
+		newFlow("d", v1alpha1.FlowPending, "v1.3.0", 1*time.Hour),
+	})
+	assert.Equal(t, &v1alpha1.PipeStatus{
+		LastFlow: &v1alpha1.PipeFlowStatus{
+			Name:  "d",
+			Phase: v1alpha1.FlowPending,
+			Ref:   "v1.3.0",
+		},
+		LastSuccessfulRef: "v1.0.0",
+		ActiveFlows:       2,
+	}, status)
+	assert.Equal(t, v1alpha1.PipeRunning, pipePhase(status))
+
+	setPipeCondition(status, triggerCondition(fmt.Errorf("invalid stage")))
+	assert.Equal(t, v1alpha1.PipeError, pipePhase(status))
+}
+
+func TestSetPipeCondition(t *testing.T) {
+	status := &v1alpha1.PipeStatus{}
+	ready := newPipeCondition(v1alpha1.PipeReady, corev1.ConditionTrue, v1alpha1.PipeReasonValid, "")
+	ready.LastTransitionTime = metav1.NewTime(time.Date(2020, 4, 25, 10, 0, 0, 0, time.UTC))
+	setPipeCondition(status, ready)
+
+	setPipeCondition(status, newPipeCondition(v1alpha1.PipeReady, corev1.ConditionTrue, v1alpha1.PipeReasonValid, ""))
+	assert.Len(t, status.Conditions, 1)
+	assert.Equal(t, ready.LastTransitionTime, status.Conditions[0].LastTransitionTime)
+
+	setPipeCondition(status, newPipeCondition(v1alpha1.PipeReady, corev1.ConditionFalse, v1alpha1.PipeReasonInvalid, ""))
+	assert.Len(t, status.Conditions, 1)
+	assert.NotEqual(t, ready.LastTransitionTime, status.Conditions[0].LastTransitionTime)
+	assert.Equal(t, v1alpha1.PipeError, pipePhase(status))
+}
@@ -3,6 +3,7 @@ package pipe
 import (
 	"crypto/md5"
 	"encoding/hex"
+	"fmt"
 	"reflect"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 	"k8s.io/klog"
 
 	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	"github.com/liubog2008/oooops/pkg/apis/mario/validation"
 	"github.com/liubog2008/oooops/pkg/utils/random"
 )
 
@@ -28,30 +30,106 @@ func (c *Controller) syncPipe(key string) error {
 		return err
 	}
 
-	pipe, err := c.pipeLister.Pipes(ns).Get(name)
+	cached, err := c.pipeLister.Pipes(ns).Get(name)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			return nil
 		}
 		return err
 	}
+	pipe := c.defaultPipe(cached)
+
+	status := pipe.Status.DeepCopy()
+	setPipeCondition(status, readyCondition(pipe))
+
+	if err := c.syncSchedule(key, pipe, status, time.Now()); err != nil {
+		return err
+	}
+
+	if err := c.syncHistory(key, pipe, time.Now()); err != nil {
+		return err
+	}
+
+	// events are kept pending until pipe is fixed
+	if !isReady(status) {
+		return c.syncPipeStatus(pipe, status)
+	}
 
 	events, err := c.listWatchedEvents(pipe)
 	if err != nil {
 		return err
 	}
 
+	// flows of other events will still be generated if one of them is failed
+	var triggerErr error
 	for _, event := range events {
+		if !isForkAllowed(pipe, event) {
+			if err := c.updateEventPipeStatus(event, &v1alpha1.EventPipeStatus{
+				Name:               pipe.Name,
+				ObservedGeneration: pipe.Generation,
+				Phase:              v1alpha1.EventIgnored,
+				Reason:             v1alpha1.EventReasonForkNotAllowed,
+				Message:            "Pull request from a fork is not allowed by pipe",
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !isPathChanged(pipe, event) {
+			if err := c.updateEventPipeStatus(event, &v1alpha1.EventPipeStatus{
+				Name:               pipe.Name,
+				ObservedGeneration: pipe.Generation,
+				Phase:              v1alpha1.EventIgnored,
+				Reason:             v1alpha1.EventReasonPathNotChanged,
+				Message:            "No path watched by pipe is changed",
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+
 		klog.V(6).Infof("consume event: %v/%v", event.Namespace, event.Name)
-		if err := c.generateFlow(pipe, event); err != nil {
+
+		flow, err := c.generateFlow(pipe, event)
+		if err != nil {
+			if updateErr := c.updateEventPipeStatus(event, &v1alpha1.EventPipeStatus{
+				Name:               pipe.Name,
+				ObservedGeneration: pipe.Generation,
+				Phase:              v1alpha1.EventFailed,
+				Reason:             v1alpha1.EventReasonGenerateFailed,
+				Message:            err.Error(),
+			}); updateErr != nil {
+				klog.Errorf("can't update status of event %s/%s: %v", event.Namespace, event.Name, updateErr)
+			}
+			if triggerErr == nil {
+				triggerErr = fmt.Errorf("can't generate flow of event %s: %v", event.Name, err)
+			}
+			continue
+		}
+
+		if err := c.updateEventPipeStatus(event, &v1alpha1.EventPipeStatus{
+			Name:               pipe.Name,
+			ObservedGeneration: pipe.Generation,
+			Phase:              v1alpha1.EventConsumed,
+			Flow:               flow,
+		}); err != nil {
 			return err
 		}
 	}
-	return nil
+
+	setPipeCondition(status, triggerCondition(triggerErr))
+
+	if err := c.syncPipeStatus(pipe, status); err != nil {
+		return err
+	}
+	return triggerErr
 }
 
+// listWatchedEvents returns events which are watched by pipe and
+// not handled by the current generation of pipe
 func (c *Controller) listWatchedEvents(pipe *v1alpha1.Pipe) ([]*v1alpha1.Event, error) {
-	events, err := c.eventLister.List(labels.Everything())
+	events, err := c.eventLister.Events(pipe.Namespace).List(labels.Everything())
 	if err != nil {
 		return nil, err
 	}
@@ -59,7 +137,7 @@ func (c *Controller) listWatchedEvents(pipe *v1alpha1.Pipe) ([]*v1alpha1.Event,
 	watched := []*v1alpha1.Event{}
 
 	for _, e := range events {
-		if isWatched(pipe, e) {
+		if isWatched(pipe, e) && !isHandled(pipe, e) {
 			watched = append(watched, e)
 		}
 	}
@@ -69,11 +147,12 @@ func (c *Controller) listWatchedEvents(pipe *v1alpha1.Pipe) ([]*v1alpha1.Event,
 	return watched, nil
 }
 
-func (c *Controller) generateFlow(pipe *v1alpha1.Pipe, event *v1alpha1.Event) error {
+// generateFlow creates or updates flow of event and returns name of the flow
+func (c *Controller) generateFlow(pipe *v1alpha1.Pipe, event *v1alpha1.Event) (string, error) {
 	klog.Infof("TODO: generate flow for pipe %s/%s, event: %s/%s", pipe.Namespace, pipe.Name, event.Namespace, event.Name)
 
 	ns := pipe.Namespace
-	hashCode := hash(event)
+	hashCode := hash(pipe, event)
 
 	flows, err := c.flowLister.Flows(ns).List(labels.SelectorFromValidatedSet(
 		labels.Set{
@@ -81,7 +160,7 @@ func (c *Controller) generateFlow(pipe *v1alpha1.Pipe, event *v1alpha1.Event) er
 		},
 	))
 	if err != nil {
-		return err
+		return "", err
 	}
 	name := genName(pipe)
 
@@ -112,46 +191,61 @@ func (c *Controller) generateFlow(pipe *v1alpha1.Pipe, event *v1alpha1.Event) er
 			Selector: selector,
 			Git:      pipeSpec.Git,
 			Stages:   pipeSpec.Stages,
+			Timeout:  pipeSpec.Timeout,
+			Extra:    event.Spec.Extra,
+
+			AllowedSecrets: pipeSpec.AllowedSecrets,
+
+			ConcurrencyPolicy: pipeSpec.ConcurrencyPolicy,
+			ConcurrencyGroup:  concurrencyGroup(pipe, event),
 		},
 		Status: v1alpha1.FlowStatus{
 			Phase: v1alpha1.FlowPending,
 		},
 	}
 	expectedFlow.Spec.Git.Ref = event.Spec.Ref
+	expectedFlow.Spec.Git.BaseRef = mergeBaseRef(pipe, event)
 
-	// no flow is selected
-	if len(flows) == 0 {
-		if _, err := c.extClient.MarioV1alpha1().Flows(ns).Create(expectedFlow); err != nil {
-			return err
-		}
-		return nil
-	}
-
+	generated := ""
 	for _, flow := range flows {
 		// ignore flow which is not controlled by this pipe
 		if !metav1.IsControlledBy(flow, pipe) {
-			return nil
+			continue
 		}
 		// ignore flow which is not triggered by this event
 		if !isTriggeredBy(flow, event) {
-			return nil
+			continue
 		}
+		generated = flow.Name
 
 		if !sementicEqual(flow, expectedFlow) {
 			updating := flow.DeepCopy()
 			updating.Spec.Mario = nil
 			updating.Spec.Git = expectedFlow.Spec.Git
 			updating.Spec.Stages = expectedFlow.Spec.Stages
+			updating.Spec.Timeout = expectedFlow.Spec.Timeout
+			updating.Spec.Extra = expectedFlow.Spec.Extra
+			updating.Spec.AllowedSecrets = expectedFlow.Spec.AllowedSecrets
+			updating.Spec.ConcurrencyPolicy = expectedFlow.Spec.ConcurrencyPolicy
+			updating.Spec.ConcurrencyGroup = expectedFlow.Spec.ConcurrencyGroup
 
 			updating.Status.Phase = v1alpha1.FlowPending
 
 			if _, err := c.extClient.MarioV1alpha1().Flows(ns).Update(updating); err != nil {
-				return nil
+				return "", err
 			}
 		}
 	}
 
-	return nil
+	if generated != "" {
+		return generated, nil
+	}
+
+	// no flow is selected
+	if _, err := c.extClient.MarioV1alpha1().Flows(ns).Create(expectedFlow); err != nil {
+		return "", err
+	}
+	return name, nil
 }
 
 func sementicEqual(a, b *v1alpha1.Flow) bool {
@@ -161,28 +255,50 @@ func sementicEqual(a, b *v1alpha1.Flow) bool {
 	if !reflect.DeepEqual(&a.Spec.Stages, &b.Spec.Stages) {
 		return false
 	}
+	if !reflect.DeepEqual(a.Spec.Timeout, b.Spec.Timeout) {
+		return false
+	}
+	if a.Spec.ConcurrencyPolicy != b.Spec.ConcurrencyPolicy || a.Spec.ConcurrencyGroup != b.Spec.ConcurrencyGroup {
+		return false
+	}
+	// nil and empty slices or maps are equal
+	if (len(a.Spec.AllowedSecrets) != 0 || len(b.Spec.AllowedSecrets) != 0) &&
+		!reflect.DeepEqual(a.Spec.AllowedSecrets, b.Spec.AllowedSecrets) {
+		return false
+	}
+	if (len(a.Spec.Extra) != 0 || len(b.Spec.Extra) != 0) && !reflect.DeepEqual(a.Spec.Extra, b.Spec.Extra) {
+		return false
+	}
 	return true
 }
 
-// hash generate event identity
-// NOTE(liubog2008): maybe change to event UID?
-func hash(event *v1alpha1.Event) string {
-	repo := event.Spec.Repo
-	ref := event.Spec.Ref
+// hash generates revision identity of flow from event.
+// Every event and commit gets its own flow by default,
+// events with the same repo and ref share one flow if pipe dedupes them
+func hash(pipe *v1alpha1.Pipe, event *v1alpha1.Event) string {
+	identity := string(event.UID) + "@" + event.Spec.Extra[v1alpha1.EventExtraCommit]
+
+	if pipe.Spec.Dedupe {
+		identity = event.Spec.Repo + "@" + event.Spec.Ref
+		// scheduled events with the same ref should generate different flows
+		if event.Spec.When == v1alpha1.Schedule {
+			identity += "@" + event.Spec.Extra[v1alpha1.EventExtraScheduledTime]
+		}
+	}
 
 	hasher := md5.New()
-	hasher.Write([]byte(repo + "@" + ref))
+	hasher.Write([]byte(identity))
 	code := hex.EncodeToString(hasher.Sum(nil))
 	return code[:11]
 }
 
 // genName generates name of flow
 func genName(pipe *v1alpha1.Pipe) string {
-	return pipe.Name + "-" + random.Random(7)
+	return pipe.Name + "-" + random.Random(validation.FlowNameSuffixLength)
 }
 
 func isTriggeredBy(flow *v1alpha1.Flow, event *v1alpha1.Event) bool {
-	if flow.Spec.Git.Repo != event.Spec.Repo {
+	if !isSameRepo(flow.Spec.Git.Repo, event.Spec.Repo) {
 		return false
 	}
 
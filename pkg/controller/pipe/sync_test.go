@@ -0,0 +1,47 @@
+package pipe
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+func TestHash(t *testing.T) {
+	newEvent := func(uid, commit string) *v1alpha1.Event {
+		return &v1alpha1.Event{
+			ObjectMeta: metav1.ObjectMeta{
+				UID: types.UID(uid),
+			},
+			Spec: v1alpha1.EventSpec{
+				Repo: "https://github.com/liubog2008/oooops",
+				When: v1alpha1.Push,
+				Ref:  "refs/heads/master",
+				Extra: map[string]string{
+					v1alpha1.EventExtraCommit: commit,
+				},
+			},
+		}
+	}
+
+	pipe := &v1alpha1.Pipe{}
+	dedupe := &v1alpha1.Pipe{
+		Spec: v1alpha1.PipeSpec{
+			Dedupe: true,
+		},
+	}
+
+	first := newEvent("a", "1111111")
+	resent := newEvent("b", "1111111")
+	pushed := newEvent("c", "2222222")
+
+	assert.Equal(t, hash(pipe, first), hash(pipe, first.DeepCopy()))
+	assert.NotEqual(t, hash(pipe, first), hash(pipe, resent))
+	assert.NotEqual(t, hash(pipe, first), hash(pipe, pushed))
+
+	assert.Equal(t, hash(dedupe, first), hash(dedupe, resent))
+	assert.Equal(t, hash(dedupe, first), hash(dedupe, pushed))
+}
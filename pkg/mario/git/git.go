@@ -19,6 +19,8 @@ const (
 // Interface defines git interface which is used by mario
 type Interface interface {
 	Verify(remote, ref string) error
+	// Ref returns full name of the current ref, commit is returned if HEAD is detached
+	Ref() (string, error)
 }
 
 type gitCmd struct {
@@ -120,6 +122,25 @@ func (c *gitCmd) CommitIsMatched(ref string) error {
 	)
 }
 
+// Ref returns full name of the current ref by running
+// command "git rev-parse --symbolic-full-name HEAD"
+func (c *gitCmd) Ref() (string, error) {
+	output, err := c.retry(retryTimes, "rev-parse", "--symbolic-full-name", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	ref := strings.TrimSpace(string(output))
+	if ref != "HEAD" {
+		return ref, nil
+	}
+	// HEAD is detached
+	output, err = c.retry(retryTimes, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
 func (c *gitCmd) retry(retries int, args ...string) ([]byte, error) {
 	var (
 		lastError error
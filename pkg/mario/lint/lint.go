@@ -0,0 +1,234 @@
+// Package lint checks mario file offline, so that problems can be found
+// before the file is consumed by mario job of a flow
+package lint
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	"github.com/liubog2008/oooops/pkg/apis/mario/validation"
+	"github.com/liubog2008/oooops/pkg/client/clientset/scheme"
+	"github.com/liubog2008/oooops/pkg/mario/template"
+)
+
+// Issue defines a problem found by lint
+type Issue struct {
+	// File defines path of file which has the problem
+	File string `json:"file"`
+	// Field defines path of field which has the problem,
+	// it is empty if the file can't be decoded
+	Field string `json:"field,omitempty"`
+	// Message defines detail of the problem
+	Message string `json:"message"`
+}
+
+// Config defines files to lint
+type Config struct {
+	// MarioFile defines path of mario file
+	MarioFile string
+	// PipeFile defines path of pipe whose stages reference actions of mario,
+	// it is optional
+	PipeFile string
+	// ActionFiles defines paths of actions which can be imported by mario,
+	// imports are not resolved if no action file is specified
+	ActionFiles []string
+	// Namespace defines namespace of mario, it is used to resolve imports
+	// and actions without namespace
+	Namespace string
+}
+
+// Lint checks mario file and returns problems found,
+// error is returned only if files can't be read
+func Lint(c *Config) ([]Issue, error) {
+	issues := []Issue{}
+
+	mario := v1alpha1.Mario{}
+	ok, err := decodeFile(c.MarioFile, &mario, &issues)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return issues, nil
+	}
+
+	var actions template.Actions
+	if len(c.ActionFiles) != 0 {
+		actions = template.Actions{}
+	}
+	for _, file := range c.ActionFiles {
+		action := v1alpha1.Action{}
+		ok, err := decodeFile(file, &action, &issues)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		actions.Add(c.Namespace, &action)
+	}
+
+	errs := validation.ValidateMario(&mario)
+	templates, templateErrs := lintActions(&mario, c.Namespace, actions)
+	errs = append(errs, templateErrs...)
+	issues = appendIssues(issues, c.MarioFile, errs)
+
+	if c.PipeFile == "" {
+		return issues, nil
+	}
+
+	pipe := v1alpha1.Pipe{}
+	ok, err = decodeFile(c.PipeFile, &pipe, &issues)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return issues, nil
+	}
+	// action of stage defaults to name of stage
+	scheme.Scheme.Default(&pipe)
+
+	fldPath := field.NewPath("spec", "stages")
+	errs = validation.ValidateStages(pipe.Spec.Stages, "", fldPath)
+	errs = append(errs, lintStages(pipe.Spec.Stages, &mario, templates, fldPath)...)
+	issues = appendIssues(issues, c.PipeFile, errs)
+
+	return issues, nil
+}
+
+// decodeFile decodes file with codecs used by mario,
+// decoding error is recorded as an issue
+func decodeFile(file string, obj runtime.Object, issues *[]Issue) (bool, error) {
+	body, err := ioutil.ReadFile(file)
+	if err != nil {
+		return false, err
+	}
+
+	if err := template.Decode(body, obj); err != nil {
+		*issues = append(*issues, Issue{
+			File:    file,
+			Message: err.Error(),
+		})
+		return false, nil
+	}
+	return true, nil
+}
+
+func appendIssues(issues []Issue, file string, errs field.ErrorList) []Issue {
+	for _, err := range errs {
+		issues = append(issues, Issue{
+			File:    file,
+			Field:   err.Field,
+			Message: err.ErrorBody(),
+		})
+	}
+	return issues
+}
+
+// lintActions checks imports and templates of actions, it returns
+// resolved templates by name of action.
+// Templates of imported actions are unknown if actions is nil
+func lintActions(
+	mario *v1alpha1.Mario,
+	namespace string,
+	actions template.Actions,
+) (map[string]*v1alpha1.ActionTemplate, field.ErrorList) {
+	allErrs := field.ErrorList{}
+	fldPath := field.NewPath("spec")
+
+	if actions != nil {
+		for i, path := range mario.Spec.Imports {
+			if _, err := actions.Get(namespace, path); err != nil {
+				allErrs = append(allErrs, field.NotFound(fldPath.Child("imports").Index(i), path))
+			}
+		}
+	}
+
+	templates := map[string]*v1alpha1.ActionTemplate{}
+	for i := range mario.Spec.Actions {
+		action := &mario.Spec.Actions[i]
+		idxPath := fldPath.Child("actions").Index(i)
+
+		tmpl := action.Template
+		if tmpl == nil {
+			if !template.IsImported(mario, action.Name) {
+				allErrs = append(allErrs, field.Required(idxPath.Child("template"),
+					"action is neither defined nor imported"))
+				continue
+			}
+			// unresolved imports have been reported
+			imported, err := actions.Get(namespace, action.Name)
+			if err != nil {
+				continue
+			}
+			rendered, err := template.Render(&imported.Spec, action.Args)
+			if err != nil {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("args"), action.Args, err.Error()))
+				continue
+			}
+			tmpl = rendered
+		}
+
+		for j := range action.Env {
+			env := &action.Env[j]
+			if env.Name == tmpl.Version.EnvName {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("envs").Index(j).Child("name"), env.Name,
+					"conflicts with version env of action template"))
+			}
+		}
+
+		if _, ok := templates[action.Name]; !ok {
+			templates[action.Name] = tmpl
+		}
+	}
+
+	return templates, allErrs
+}
+
+// lintStages checks that stages reference actions of mario and
+// matrix parameters don't conflict with envs of actions
+func lintStages(
+	stages []v1alpha1.Stage,
+	mario *v1alpha1.Mario,
+	templates map[string]*v1alpha1.ActionTemplate,
+	fldPath *field.Path,
+) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	for i := range stages {
+		stage := &stages[i]
+		idxPath := fldPath.Index(i)
+
+		if stage.Action == "" || strings.HasPrefix(stage.Action, v1alpha1.SystemActionPrefix) {
+			continue
+		}
+
+		action := template.FindAction(mario, stage.Action)
+		if action == nil {
+			allErrs = append(allErrs, field.NotFound(idxPath.Child("action"), stage.Action))
+			continue
+		}
+
+		envs := map[string]struct{}{}
+		for j := range action.Env {
+			envs[action.Env[j].Name] = struct{}{}
+		}
+		tmpl := templates[action.Name]
+
+		for j := range stage.Matrix {
+			p := &stage.Matrix[j]
+			namePath := idxPath.Child("matrix").Index(j).Child("name")
+			if tmpl != nil && p.Name == tmpl.Version.EnvName {
+				allErrs = append(allErrs, field.Invalid(namePath, p.Name, "conflicts with version env of action template"))
+			}
+			if _, ok := envs[p.Name]; ok {
+				allErrs = append(allErrs, field.Invalid(namePath, p.Name, "conflicts with env of action"))
+			}
+		}
+	}
+
+	return allErrs
+}
@@ -0,0 +1,126 @@
+package lint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testMario = `apiVersion: mario.oooops.com/v1alpha1
+kind: Mario
+metadata:
+  name: test
+spec:
+  imports:
+  - go-test
+  - deploy
+  actions:
+  - name: compile
+    template:
+      image: golang:1.13
+      version:
+        envName: VERSION
+    envs:
+    - name: VERSION
+      value: v1
+  - name: compile
+    template:
+      image: golang:1.13
+  - name: go-test
+  - name: lint
+`
+
+const testAction = `apiVersion: mario.oooops.com/v1alpha1
+kind: Action
+metadata:
+  name: go-test
+spec:
+  template:
+    image: golang:$(args.version)
+  args:
+  - name: version
+`
+
+const testPipe = `apiVersion: mario.oooops.com/v1alpha1
+kind: Pipe
+metadata:
+  name: test
+spec:
+  stages:
+  - name: compile
+    matrix:
+    - name: VERSION
+      values: ["1", "2"]
+  - name: unknown
+  - name: deploy
+    action: system::deploy
+`
+
+func TestLint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lint")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) string {
+		file := filepath.Join(dir, name)
+		assert.NoError(t, ioutil.WriteFile(file, []byte(content), 0644))
+		return file
+	}
+	marioFile := write(".mario.yaml", testMario)
+	actionFile := write("action.yaml", testAction)
+	pipeFile := write("pipe.yaml", testPipe)
+	brokenFile := write("broken.yaml", "spec: [\n")
+
+	fields := func(issues []Issue) []string {
+		s := []string{}
+		for _, issue := range issues {
+			s = append(s, issue.File+":"+issue.Field)
+		}
+		return s
+	}
+
+	issues, err := Lint(&Config{
+		MarioFile: marioFile,
+		Namespace: "default",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		marioFile + ":spec.actions[1].name",
+		marioFile + ":spec.actions[0].envs[0].name",
+		marioFile + ":spec.actions[3].template",
+	}, fields(issues))
+
+	issues, err = Lint(&Config{
+		MarioFile:   marioFile,
+		PipeFile:    pipeFile,
+		ActionFiles: []string{actionFile},
+		Namespace:   "default",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		marioFile + ":spec.actions[1].name",
+		marioFile + ":spec.imports[1]",
+		marioFile + ":spec.actions[0].envs[0].name",
+		marioFile + ":spec.actions[2].args",
+		marioFile + ":spec.actions[3].template",
+		pipeFile + ":spec.stages[0].matrix[0].name",
+		pipeFile + ":spec.stages[0].matrix[0].name",
+		pipeFile + ":spec.stages[1].action",
+	}, fields(issues))
+
+	issues, err = Lint(&Config{
+		MarioFile: brokenFile,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{brokenFile + ":"}, fields(issues))
+
+	_, err = Lint(&Config{
+		MarioFile: filepath.Join(dir, "not-found.yaml"),
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,240 @@
+// Package runner runs mario actions as local processes,
+// so that stages can be reproduced without a cluster
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	"github.com/liubog2008/oooops/pkg/client/clientset/scheme"
+	"github.com/liubog2008/oooops/pkg/mario/stages"
+	"github.com/liubog2008/oooops/pkg/mario/template"
+)
+
+// Interface defines interface to run actions locally
+type Interface interface {
+	// RunAction runs action of mario
+	RunAction(stopCh <-chan struct{}, name string) error
+	// RunStages runs stages of pipe in order of dependencies and stops at the first failure,
+	// stages whose conditions are not matched by ref are skipped
+	RunStages(stopCh <-chan struct{}) error
+}
+
+// Config defines config to run actions
+type Config struct {
+	// MarioFile defines path of mario file
+	MarioFile string
+	// PipeFile defines path of pipe whose stages will be run
+	PipeFile string
+	// ActionFiles defines paths of actions which can be imported by mario
+	ActionFiles []string
+	// Namespace defines namespace of mario to resolve imports
+	Namespace string
+
+	// Dir defines root dir of git project, actions are run in it
+	Dir string
+	// Ref defines git ref to match conditions of stages,
+	// stages with conditions of extra info are always skipped
+	Ref string
+	// Version defines value of version env
+	Version string
+
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+type runner struct {
+	namespace string
+	dir       string
+	ref       string
+	version   string
+
+	stdout io.Writer
+	stderr io.Writer
+
+	mario   *v1alpha1.Mario
+	pipe    *v1alpha1.Pipe
+	graph   *stages.Graph
+	actions template.Actions
+}
+
+// New returns a runner interface, files are decoded with codecs used by mario
+func New(c *Config) (Interface, error) {
+	r := runner{
+		namespace: c.Namespace,
+		dir:       c.Dir,
+		ref:       c.Ref,
+		version:   c.Version,
+		stdout:    c.Stdout,
+		stderr:    c.Stderr,
+		actions:   template.Actions{},
+	}
+
+	mario := v1alpha1.Mario{}
+	if err := template.DecodeFile(c.MarioFile, &mario); err != nil {
+		return nil, err
+	}
+	r.mario = &mario
+
+	if c.PipeFile != "" {
+		pipe := v1alpha1.Pipe{}
+		if err := template.DecodeFile(c.PipeFile, &pipe); err != nil {
+			return nil, err
+		}
+		// action of stage defaults to name of stage
+		scheme.Scheme.Default(&pipe)
+		graph, err := stages.NewGraph(pipe.Spec.Stages)
+		if err != nil {
+			return nil, err
+		}
+		r.pipe = &pipe
+		r.graph = graph
+	}
+
+	for _, file := range c.ActionFiles {
+		action := v1alpha1.Action{}
+		if err := template.DecodeFile(file, &action); err != nil {
+			return nil, err
+		}
+		r.actions.Add(c.Namespace, &action)
+	}
+
+	return &r, nil
+}
+
+func (r *runner) RunAction(stopCh <-chan struct{}, name string) error {
+	action := template.FindAction(r.mario, name)
+	if action == nil {
+		return fmt.Errorf("action %s is not found in mario", name)
+	}
+
+	ctx, cancel := contextWithStop(stopCh, action.Timeout)
+	defer cancel()
+
+	return r.run(ctx, action, nil)
+}
+
+func (r *runner) RunStages(stopCh <-chan struct{}) error {
+	if r.pipe == nil {
+		return fmt.Errorf("pipe is not specified")
+	}
+
+	for _, i := range r.graph.Sort() {
+		stage := &r.pipe.Spec.Stages[i]
+
+		if !stages.MatchCondition(stage.When, r.ref, nil) {
+			klog.Infof("skip stage %s, conditions are not matched by ref %s", stage.Name, r.ref)
+			continue
+		}
+
+		if strings.HasPrefix(stage.Action, v1alpha1.SystemActionPrefix) {
+			klog.Warningf("skip stage %s, system action %s can't be run locally", stage.Name, stage.Action)
+			continue
+		}
+
+		action := template.FindAction(r.mario, stage.Action)
+		if action == nil {
+			return fmt.Errorf("action %s of stage %s is not found in mario", stage.Action, stage.Name)
+		}
+
+		// timeout of stage overrides timeout of action
+		timeout := action.Timeout
+		if stage.Timeout != nil {
+			timeout = stage.Timeout
+		}
+
+		if err := r.runStage(stopCh, stage, action, timeout); err != nil {
+			return fmt.Errorf("stage %s failed: %v", stage.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *runner) runStage(
+	stopCh <-chan struct{},
+	stage *v1alpha1.Stage,
+	action *v1alpha1.MarioAction,
+	timeout *metav1.Duration,
+) error {
+	ctx, cancel := contextWithStop(stopCh, timeout)
+	defer cancel()
+
+	for _, params := range template.ExpandMatrix(stage.Matrix) {
+		klog.Infof("run stage %s with parameters %v", stage.Name, params)
+		if err := r.run(ctx, action, params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// run runs action as a local process in root dir of git project,
+// env of action is constructed as the one of action job
+func (r *runner) run(ctx context.Context, action *v1alpha1.MarioAction, params []corev1.EnvVar) error {
+	tmpl, err := template.Resolve(r.namespace, r.mario, action, r.actions.Get)
+	if err != nil {
+		return err
+	}
+
+	env, err := template.Env(action, tmpl, r.version, params)
+	if err != nil {
+		return err
+	}
+
+	if len(tmpl.Command) == 0 {
+		return fmt.Errorf("action %s has no command, entrypoint of image %s can't be run locally",
+			action.Name, tmpl.Image)
+	}
+	args := append(append([]string{}, tmpl.Command[1:]...), tmpl.Args...)
+
+	cmd := exec.CommandContext(ctx, tmpl.Command[0], args...)
+	cmd.Dir = r.dir
+	cmd.Env = os.Environ()
+	for _, e := range env {
+		// version env is unset if template doesn't define its name
+		if e.Name == "" {
+			continue
+		}
+		cmd.Env = append(cmd.Env, e.Name+"="+e.Value)
+	}
+	cmd.Stdout = r.stdout
+	cmd.Stderr = r.stderr
+
+	klog.Infof("run action %s: %s", action.Name, strings.Join(append([]string{tmpl.Command[0]}, args...), " "))
+
+	return cmd.Run()
+}
+
+// contextWithStop returns a context which is cancelled
+// when stopCh is closed or it exceeds timeout
+func contextWithStop(stopCh <-chan struct{}, timeout *metav1.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if timeout == nil {
+		return ctx, cancel
+	}
+
+	timeoutCtx, timeoutCancel := context.WithTimeout(ctx, timeout.Duration)
+	return timeoutCtx, func() {
+		timeoutCancel()
+		cancel()
+	}
+}
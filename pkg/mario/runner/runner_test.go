@@ -0,0 +1,147 @@
+package runner
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testMario = `apiVersion: mario.oooops.com/v1alpha1
+kind: Mario
+metadata:
+  name: test
+spec:
+  actions:
+  - name: print
+    template:
+      command:
+      - sh
+      - -c
+      args:
+      - echo $VERSION $MARIO_TEST_OS $NAME
+      version:
+        envName: VERSION
+    envs:
+    - name: NAME
+      value: print
+  - name: fail
+    template:
+      command:
+      - "false"
+`
+
+const testPipe = `apiVersion: mario.oooops.com/v1alpha1
+kind: Pipe
+metadata:
+  name: test
+spec:
+  stages:
+  - name: print
+    matrix:
+    - name: MARIO_TEST_OS
+      values: [linux, darwin]
+  - name: fail
+  - name: after
+    action: print
+`
+
+const testOrderedPipe = `apiVersion: mario.oooops.com/v1alpha1
+kind: Pipe
+metadata:
+  name: test
+spec:
+  stages:
+  - name: second
+    action: print
+    dependsOn: [first]
+    matrix:
+    - name: MARIO_TEST_OS
+      values: [darwin]
+  - name: release
+    action: fail
+    dependsOn: [first]
+    when:
+      tags: ["v*"]
+  - name: first
+    action: print
+    matrix:
+    - name: MARIO_TEST_OS
+      values: [linux]
+`
+
+func TestRunner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "runner")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) string {
+		file := filepath.Join(dir, name)
+		assert.NoError(t, ioutil.WriteFile(file, []byte(content), 0644))
+		return file
+	}
+
+	out := bytes.Buffer{}
+	r, err := New(&Config{
+		MarioFile: write(".mario.yaml", testMario),
+		PipeFile:  write("pipe.yaml", testPipe),
+		Dir:       dir,
+		Ref:       "refs/heads/main",
+		Version:   "refs/heads/main",
+		Stdout:    &out,
+		Stderr:    &out,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	stopCh := make(chan struct{})
+
+	assert.NoError(t, r.RunAction(stopCh, "print"))
+	assert.Equal(t, "refs/heads/main print\n", out.String())
+
+	assert.Error(t, r.RunAction(stopCh, "unknown"))
+
+	out.Reset()
+	err = r.RunStages(stopCh)
+	assert.EqualError(t, err, "stage fail failed: exit status 1")
+	// stages after the failed one are not run
+	assert.Equal(t, "refs/heads/main linux print\nrefs/heads/main darwin print\n", out.String())
+}
+
+func TestRunStagesInOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "runner")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) string {
+		file := filepath.Join(dir, name)
+		assert.NoError(t, ioutil.WriteFile(file, []byte(content), 0644))
+		return file
+	}
+
+	out := bytes.Buffer{}
+	r, err := New(&Config{
+		MarioFile: write(".mario.yaml", testMario),
+		PipeFile:  write("pipe.yaml", testOrderedPipe),
+		Dir:       dir,
+		Ref:       "refs/heads/main",
+		Version:   "refs/heads/main",
+		Stdout:    &out,
+		Stderr:    &out,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// stage release is skipped because its conditions are not matched by ref
+	assert.NoError(t, r.RunStages(make(chan struct{})))
+	assert.Equal(t, "refs/heads/main linux print\nrefs/heads/main darwin print\n", out.String())
+}
@@ -0,0 +1,70 @@
+package stages
+
+import (
+	"path"
+	"strings"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+const (
+	branchRefPrefix = "refs/heads/"
+	tagRefPrefix    = "refs/tags/"
+	refPrefix       = "refs/"
+)
+
+// MatchCondition returns true if ref and extra info match the condition
+func MatchCondition(cond *v1alpha1.StageCondition, ref string, extra map[string]string) bool {
+	if cond == nil {
+		return true
+	}
+
+	if len(cond.Refs) != 0 && !matchAny(cond.Refs, ref) {
+		return false
+	}
+
+	if len(cond.Branches) != 0 {
+		branch, ok := branchName(ref)
+		if !ok || !matchAny(cond.Branches, branch) {
+			return false
+		}
+	}
+
+	if len(cond.Tags) != 0 {
+		if !strings.HasPrefix(ref, tagRefPrefix) {
+			return false
+		}
+		if !matchAny(cond.Tags, strings.TrimPrefix(ref, tagRefPrefix)) {
+			return false
+		}
+	}
+
+	for k, v := range cond.Extra {
+		actual, ok := extra[k]
+		if !ok || actual != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// branchName returns branch name of ref
+func branchName(ref string) (string, bool) {
+	if strings.HasPrefix(ref, branchRefPrefix) {
+		return strings.TrimPrefix(ref, branchRefPrefix), true
+	}
+	if !strings.HasPrefix(ref, refPrefix) {
+		return ref, true
+	}
+	return "", false
+}
+
+func matchAny(patterns []string, s string) bool {
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, s); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
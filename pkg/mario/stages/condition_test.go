@@ -0,0 +1,90 @@
+package stages
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+func TestMatchStageCondition(t *testing.T) {
+	cases := []struct {
+		desc     string
+		cond     *v1alpha1.StageCondition
+		ref      string
+		extra    map[string]string
+		expected bool
+	}{
+		{
+			desc:     "no condition",
+			ref:      "refs/heads/dev",
+			expected: true,
+		},
+		{
+			desc:     "ref is matched",
+			cond:     &v1alpha1.StageCondition{Refs: []string{"refs/heads/main"}},
+			ref:      "refs/heads/main",
+			expected: true,
+		},
+		{
+			desc:     "ref is not matched",
+			cond:     &v1alpha1.StageCondition{Refs: []string{"refs/heads/main"}},
+			ref:      "refs/heads/dev",
+			expected: false,
+		},
+		{
+			desc:     "branch pattern is matched",
+			cond:     &v1alpha1.StageCondition{Branches: []string{"main", "release-*"}},
+			ref:      "refs/heads/release-1.0",
+			expected: true,
+		},
+		{
+			desc:     "short ref is regarded as branch",
+			cond:     &v1alpha1.StageCondition{Branches: []string{"main"}},
+			ref:      "main",
+			expected: true,
+		},
+		{
+			desc:     "tag is not a branch",
+			cond:     &v1alpha1.StageCondition{Branches: []string{"*"}},
+			ref:      "refs/tags/v1.0.0",
+			expected: false,
+		},
+		{
+			desc:     "tag is matched",
+			cond:     &v1alpha1.StageCondition{Tags: []string{"v*"}},
+			ref:      "refs/tags/v1.0.0",
+			expected: true,
+		},
+		{
+			desc:     "branch is not a tag",
+			cond:     &v1alpha1.StageCondition{Tags: []string{"*"}},
+			ref:      "refs/heads/main",
+			expected: false,
+		},
+		{
+			desc: "extra is matched",
+			cond: &v1alpha1.StageCondition{
+				Branches: []string{"main"},
+				Extra:    map[string]string{"env": "prod"},
+			},
+			ref:      "refs/heads/main",
+			extra:    map[string]string{"env": "prod", "user": "test"},
+			expected: true,
+		},
+		{
+			desc: "extra is not matched",
+			cond: &v1alpha1.StageCondition{
+				Extra: map[string]string{"env": "prod"},
+			},
+			ref:      "refs/heads/main",
+			extra:    map[string]string{"env": "dev"},
+			expected: false,
+		},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.expected, MatchCondition(c.cond, c.ref, c.extra), c.desc)
+	}
+}
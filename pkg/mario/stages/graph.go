@@ -0,0 +1,219 @@
+// Package stages resolves dependencies and conditions of pipe stages,
+// it is shared by the flow controller and mario command
+package stages
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	"github.com/liubog2008/oooops/pkg/apis/mario/validation"
+)
+
+// Graph defines a DAG of flow stages
+type Graph struct {
+	stages []v1alpha1.Stage
+	// deps defines index of dependencies of each stage
+	deps [][]int
+}
+
+// NewGraph resolves dependencies of stages and returns error
+// if some dependencies are unknown, there is a cycle, conditions or matrix are invalid
+func NewGraph(stages []v1alpha1.Stage) (*Graph, error) {
+	index := map[string]int{}
+	sequential := true
+	for i := range stages {
+		stage := &stages[i]
+		if _, ok := index[stage.Name]; ok {
+			return nil, fmt.Errorf("stage %s is duplicated", stage.Name)
+		}
+		index[stage.Name] = i
+
+		if err := validation.ValidateStageCondition(stage.When); err != nil {
+			return nil, fmt.Errorf("condition of stage %s is invalid: %v", stage.Name, err)
+		}
+
+		if err := validation.ValidateMatrix(stage.Matrix); err != nil {
+			return nil, fmt.Errorf("matrix of stage %s is invalid: %v", stage.Name, err)
+		}
+
+		if len(stage.DependsOn) != 0 {
+			sequential = false
+		}
+	}
+
+	deps := make([][]int, len(stages))
+	for i := range stages {
+		stage := &stages[i]
+		// keep compatible with pipes which don't define any dependency,
+		// each stage depends on the previous one
+		if sequential {
+			if i != 0 {
+				deps[i] = []int{i - 1}
+			}
+			continue
+		}
+		for _, dep := range stage.DependsOn {
+			k, ok := index[dep]
+			if !ok {
+				return nil, fmt.Errorf("stage %s depends on unknown stage %s", stage.Name, dep)
+			}
+			deps[i] = append(deps[i], k)
+		}
+	}
+
+	g := &Graph{
+		stages: stages,
+		deps:   deps,
+	}
+
+	if cycle := g.findCycle(); len(cycle) != 0 {
+		return nil, fmt.Errorf("stages have a dependency cycle: %s", strings.Join(cycle, " -> "))
+	}
+
+	return g, nil
+}
+
+const (
+	unvisited = iota
+	visiting
+	visited
+)
+
+// findCycle returns names of stages in a cycle, or nil if graph is acyclic
+func (g *Graph) findCycle() []string {
+	states := make([]int, len(g.stages))
+	path := []int{}
+
+	var visit func(i int) []string
+	visit = func(i int) []string {
+		states[i] = visiting
+		path = append(path, i)
+		for _, dep := range g.deps[i] {
+			switch states[dep] {
+			case visiting:
+				cycle := []string{}
+				start := 0
+				for k := range path {
+					if path[k] == dep {
+						start = k
+						break
+					}
+				}
+				for _, k := range path[start:] {
+					cycle = append(cycle, g.stages[k].Name)
+				}
+				return append(cycle, g.stages[dep].Name)
+			case unvisited:
+				if cycle := visit(dep); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		states[i] = visited
+		return nil
+	}
+
+	for i := range g.stages {
+		if states[i] != unvisited {
+			continue
+		}
+		if cycle := visit(i); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// Runnable returns index of stages which have not been started and
+// whose dependencies have all been completed
+func (g *Graph) Runnable(started, completed func(stage *v1alpha1.Stage) bool) []int {
+	indexes := []int{}
+	for i := range g.stages {
+		stage := &g.stages[i]
+		if started(stage) {
+			continue
+		}
+		ready := true
+		for _, dep := range g.deps[i] {
+			if !completed(&g.stages[dep]) {
+				ready = false
+				break
+			}
+		}
+		if ready {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// Sort returns index of stages in topological order,
+// stages are kept in order of definition as far as possible
+func (g *Graph) Sort() []int {
+	indexes := make([]int, 0, len(g.stages))
+	sorted := make([]bool, len(g.stages))
+	// graph is acyclic, so at least one stage is sorted in each round
+	for len(indexes) < len(g.stages) {
+		for i := range g.stages {
+			if sorted[i] {
+				continue
+			}
+			ready := true
+			for _, dep := range g.deps[i] {
+				if !sorted[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				sorted[i] = true
+				indexes = append(indexes, i)
+			}
+		}
+	}
+	return indexes
+}
+
+// Downstream returns index of the stage and all stages which depend on it
+// directly or indirectly
+func (g *Graph) Downstream(name string) ([]int, bool) {
+	start := -1
+	for i := range g.stages {
+		if g.stages[i].Name == name {
+			start = i
+			break
+		}
+	}
+	if start == -1 {
+		return nil, false
+	}
+
+	selected := make([]bool, len(g.stages))
+	selected[start] = true
+	// select stages whose dependencies are selected until nothing changed
+	for changed := true; changed; {
+		changed = false
+		for i := range g.stages {
+			if selected[i] {
+				continue
+			}
+			for _, dep := range g.deps[i] {
+				if selected[dep] {
+					selected[i] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	indexes := []int{}
+	for i := range selected {
+		if selected[i] {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes, true
+}
@@ -0,0 +1,158 @@
+package stages
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+func TestStageGraphSequential(t *testing.T) {
+	stages := []v1alpha1.Stage{
+		{Name: "a"},
+		{Name: "b"},
+		{Name: "c"},
+	}
+	g, err := NewGraph(stages)
+	assert.NoError(t, err)
+
+	done := map[string]bool{}
+	started := func(s *v1alpha1.Stage) bool {
+		_, ok := done[s.Name]
+		return ok
+	}
+	completed := func(s *v1alpha1.Stage) bool {
+		return done[s.Name]
+	}
+
+	assert.Equal(t, []int{0}, g.Runnable(started, completed))
+
+	done["a"] = false
+	assert.Equal(t, []int{}, g.Runnable(started, completed))
+
+	done["a"] = true
+	assert.Equal(t, []int{1}, g.Runnable(started, completed))
+}
+
+func TestStageGraphParallel(t *testing.T) {
+	stages := []v1alpha1.Stage{
+		{Name: "lint"},
+		{Name: "test"},
+		{Name: "scan"},
+		{Name: "build", DependsOn: []string{"lint", "test"}},
+		{Name: "deploy", DependsOn: []string{"build", "scan"}},
+	}
+	g, err := NewGraph(stages)
+	assert.NoError(t, err)
+
+	done := map[string]bool{}
+	started := func(s *v1alpha1.Stage) bool {
+		_, ok := done[s.Name]
+		return ok
+	}
+	completed := func(s *v1alpha1.Stage) bool {
+		return done[s.Name]
+	}
+
+	assert.Equal(t, []int{0, 1, 2}, g.Runnable(started, completed))
+
+	done["lint"] = true
+	done["test"] = false
+	done["scan"] = true
+	assert.Equal(t, []int{}, g.Runnable(started, completed))
+
+	done["test"] = true
+	assert.Equal(t, []int{3}, g.Runnable(started, completed))
+
+	done["build"] = true
+	assert.Equal(t, []int{4}, g.Runnable(started, completed))
+}
+
+func TestStageGraphSort(t *testing.T) {
+	stages := []v1alpha1.Stage{
+		{Name: "deploy", DependsOn: []string{"build"}},
+		{Name: "build", DependsOn: []string{"test"}},
+		{Name: "lint"},
+		{Name: "test"},
+	}
+	g, err := NewGraph(stages)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []int{2, 3, 1, 0}, g.Sort())
+}
+
+func TestStageGraphInvalid(t *testing.T) {
+	cases := []struct {
+		desc   string
+		stages []v1alpha1.Stage
+	}{
+		{
+			desc: "unknown dependency",
+			stages: []v1alpha1.Stage{
+				{Name: "a", DependsOn: []string{"b"}},
+			},
+		},
+		{
+			desc: "duplicated stage",
+			stages: []v1alpha1.Stage{
+				{Name: "a"},
+				{Name: "a"},
+			},
+		},
+		{
+			desc: "self cycle",
+			stages: []v1alpha1.Stage{
+				{Name: "a", DependsOn: []string{"a"}},
+			},
+		},
+		{
+			desc: "cycle",
+			stages: []v1alpha1.Stage{
+				{Name: "a", DependsOn: []string{"c"}},
+				{Name: "b", DependsOn: []string{"a"}},
+				{Name: "c", DependsOn: []string{"b"}},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		_, err := NewGraph(c.stages)
+		assert.Error(t, err, c.desc)
+	}
+}
+
+func TestStageGraphDownstream(t *testing.T) {
+	stages := []v1alpha1.Stage{
+		{Name: "lint"},
+		{Name: "test"},
+		{Name: "build", DependsOn: []string{"lint", "test"}},
+		{Name: "deploy", DependsOn: []string{"build"}},
+		{Name: "scan"},
+	}
+	g, err := NewGraph(stages)
+	assert.NoError(t, err)
+
+	indexes, ok := g.Downstream("test")
+	assert.True(t, ok)
+	assert.Equal(t, []int{1, 2, 3}, indexes)
+
+	indexes, ok = g.Downstream("scan")
+	assert.True(t, ok)
+	assert.Equal(t, []int{4}, indexes)
+
+	_, ok = g.Downstream("unknown")
+	assert.False(t, ok)
+
+	sequential, err := NewGraph([]v1alpha1.Stage{
+		{Name: "a"},
+		{Name: "b"},
+		{Name: "c"},
+	})
+	assert.NoError(t, err)
+
+	indexes, ok = sequential.Downstream("b")
+	assert.True(t, ok)
+	assert.Equal(t, []int{1, 2}, indexes)
+}
@@ -0,0 +1,72 @@
+package template
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+// Env returns env of action, it contains version env of template,
+// matrix parameters and env of action in order
+func Env(
+	action *v1alpha1.MarioAction,
+	tmpl *v1alpha1.ActionTemplate,
+	version string,
+	params []corev1.EnvVar,
+) ([]corev1.EnvVar, error) {
+	env := make([]corev1.EnvVar, 0, len(action.Env)+len(params)+1)
+	env = append(env, corev1.EnvVar{
+		Name:  tmpl.Version.EnvName,
+		Value: version,
+	})
+
+	names := map[string]struct{}{}
+	for _, p := range params {
+		if p.Name == tmpl.Version.EnvName {
+			return nil, fmt.Errorf("matrix parameter %s is confict with version env", p.Name)
+		}
+		names[p.Name] = struct{}{}
+		env = append(env, p)
+	}
+
+	for i := range action.Env {
+		e := &action.Env[i]
+		if e.Name == tmpl.Version.EnvName {
+			return nil, fmt.Errorf("set an env whose name is confict with version env")
+		}
+		if _, ok := names[e.Name]; ok {
+			return nil, fmt.Errorf("set an env whose name is confict with matrix parameter %s", e.Name)
+		}
+		env = append(env, corev1.EnvVar{
+			Name:  e.Name,
+			Value: e.Value,
+		})
+	}
+
+	return env, nil
+}
+
+// ExpandMatrix returns parameters of all combinations of matrix in order,
+// one empty combination is returned if matrix is empty
+func ExpandMatrix(matrix []v1alpha1.MatrixParameter) [][]corev1.EnvVar {
+	combinations := [][]corev1.EnvVar{{}}
+	for i := range matrix {
+		p := &matrix[i]
+		expanded := make([][]corev1.EnvVar, 0, len(combinations)*len(p.Values))
+		for _, c := range combinations {
+			for _, v := range p.Values {
+				params := make([]corev1.EnvVar, 0, len(c)+1)
+				params = append(params, c...)
+				params = append(params, corev1.EnvVar{
+					Name:  p.Name,
+					Value: v,
+				})
+				expanded = append(expanded, params)
+			}
+		}
+		combinations = expanded
+	}
+	return combinations
+}
@@ -0,0 +1,55 @@
+package template
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	"github.com/liubog2008/oooops/pkg/client/clientset/scheme"
+)
+
+// Actions defines imported actions read from files by namespace and name
+type Actions map[string]*v1alpha1.Action
+
+// Add adds action, namespace is used if action has no namespace
+func (a Actions) Add(namespace string, action *v1alpha1.Action) {
+	ns := action.Namespace
+	if ns == "" {
+		ns = namespace
+	}
+	a[actionKey(ns, action.Name)] = action
+}
+
+// Get returns imported action by namespace and name, it can be used as GetFunc
+func (a Actions) Get(namespace, name string) (*v1alpha1.Action, error) {
+	action, ok := a[actionKey(namespace, name)]
+	if !ok {
+		return nil, fmt.Errorf("imported action %s/%s is not found", namespace, name)
+	}
+	return action, nil
+}
+
+func actionKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// Decode decodes data with codecs used by mario
+func Decode(data []byte, obj runtime.Object) error {
+	decoder := scheme.Codecs.UniversalDecoder(v1alpha1.SchemeGroupVersion)
+	_, _, err := decoder.Decode(data, nil, obj)
+	return err
+}
+
+// DecodeFile reads file and decodes it with codecs used by mario
+func DecodeFile(file string, obj runtime.Object) error {
+	body, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if err := Decode(body, obj); err != nil {
+		return fmt.Errorf("can't decode %s: %v", file, err)
+	}
+	return nil
+}
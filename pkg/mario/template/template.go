@@ -0,0 +1,109 @@
+// Package template resolves templates of mario actions,
+// it is shared by the flow controller and mario command
+package template
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+const (
+	argRefPrefix = "$(args."
+	argRefSuffix = ")"
+)
+
+// GetFunc returns imported action by namespace and name
+type GetFunc func(namespace, name string) (*v1alpha1.Action, error)
+
+// Resolve returns template of the action,
+// if action is imported, template will be rendered by args of action
+func Resolve(namespace string, mario *v1alpha1.Mario, action *v1alpha1.MarioAction, get GetFunc) (*v1alpha1.ActionTemplate, error) {
+	if action.Template != nil {
+		return action.Template, nil
+	}
+
+	if !IsImported(mario, action.Name) {
+		return nil, fmt.Errorf("no action template of %s, it is neither defined nor imported", action.Name)
+	}
+
+	imported, err := get(namespace, action.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return Render(&imported.Spec, action.Args)
+}
+
+// IsImported returns true if action with the name is imported by mario,
+// only actions in the same namespace of flow can be imported
+func IsImported(mario *v1alpha1.Mario, actionName string) bool {
+	for _, path := range mario.Spec.Imports {
+		if path == actionName {
+			return true
+		}
+	}
+	return false
+}
+
+// FindAction returns action of mario by name
+func FindAction(mario *v1alpha1.Mario, name string) *v1alpha1.MarioAction {
+	if mario == nil {
+		return nil
+	}
+	for i := range mario.Spec.Actions {
+		action := &mario.Spec.Actions[i]
+		if action.Name == name {
+			return action
+		}
+	}
+	return nil
+}
+
+// Render validates args and replaces references of args in template
+func Render(spec *v1alpha1.ActionSpec, args map[string]string) (*v1alpha1.ActionTemplate, error) {
+	if spec.Template == nil {
+		return nil, fmt.Errorf("imported action has no template")
+	}
+
+	values := map[string]string{}
+	for i := range spec.Args {
+		arg := &spec.Args[i]
+		v, ok := args[arg.Name]
+		if !ok && !arg.Optional {
+			return nil, fmt.Errorf("arg %s is required", arg.Name)
+		}
+		values[arg.Name] = v
+	}
+	for name := range args {
+		if _, ok := values[name]; !ok {
+			return nil, fmt.Errorf("arg %s is not defined by imported action", name)
+		}
+	}
+
+	render := func(s string) string {
+		for name, v := range values {
+			s = strings.Replace(s, argRefPrefix+name+argRefSuffix, v, -1)
+		}
+		return s
+	}
+	renderAll := func(ss []string) []string {
+		if ss == nil {
+			return nil
+		}
+		rendered := make([]string, 0, len(ss))
+		for _, s := range ss {
+			rendered = append(rendered, render(s))
+		}
+		return rendered
+	}
+
+	tmpl := spec.Template.DeepCopy()
+	tmpl.Image = render(tmpl.Image)
+	tmpl.Command = renderAll(tmpl.Command)
+	tmpl.Args = renderAll(tmpl.Args)
+	tmpl.WorkingDir = render(tmpl.WorkingDir)
+
+	return tmpl, nil
+}
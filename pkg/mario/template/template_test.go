@@ -0,0 +1,93 @@
+package template
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+func TestRender(t *testing.T) {
+	spec := v1alpha1.ActionSpec{
+		Template: &v1alpha1.ActionTemplate{
+			Image:   "golang:$(args.version)",
+			Command: []string{"go", "test"},
+			Args:    []string{"$(args.flags)", "./..."},
+		},
+		Args: []v1alpha1.ActionArg{
+			{Name: "version"},
+			{Name: "flags", Optional: true},
+		},
+	}
+
+	tmpl, err := Render(&spec, map[string]string{
+		"version": "1.13",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "golang:1.13", tmpl.Image)
+	assert.Equal(t, []string{"go", "test"}, tmpl.Command)
+	assert.Equal(t, []string{"", "./..."}, tmpl.Args)
+	// template of imported action should not be changed
+	assert.Equal(t, "golang:$(args.version)", spec.Template.Image)
+
+	_, err = Render(&spec, map[string]string{})
+	assert.Error(t, err, "required arg is missing")
+
+	_, err = Render(&spec, map[string]string{
+		"version": "1.13",
+		"unknown": "",
+	})
+	assert.Error(t, err, "unknown arg is set")
+}
+
+func TestResolve(t *testing.T) {
+	get := func(ns, name string) (*v1alpha1.Action, error) {
+		if ns != "default" || name != "go-test" {
+			return nil, fmt.Errorf("action %s/%s is not found", ns, name)
+		}
+		return &v1alpha1.Action{
+			Spec: v1alpha1.ActionSpec{
+				Template: &v1alpha1.ActionTemplate{Image: "golang"},
+			},
+		}, nil
+	}
+	action := &v1alpha1.MarioAction{Name: "go-test"}
+
+	tmpl, err := Resolve("default", &v1alpha1.Mario{
+		Spec: v1alpha1.MarioSpec{Imports: []string{"go-test"}},
+	}, action, get)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "golang", tmpl.Image)
+	}
+
+	// actions of other namespaces can't be imported
+	_, err = Resolve("default", &v1alpha1.Mario{
+		Spec: v1alpha1.MarioSpec{Imports: []string{"shared/go-test"}},
+	}, action, get)
+	assert.Error(t, err)
+
+	_, err = Resolve("other", &v1alpha1.Mario{
+		Spec: v1alpha1.MarioSpec{Imports: []string{"go-test"}},
+	}, action, get)
+	assert.Error(t, err)
+}
+
+func TestActions(t *testing.T) {
+	actions := Actions{}
+	actions.Add("default", &v1alpha1.Action{
+		ObjectMeta: metav1.ObjectMeta{Name: "go-test"},
+	})
+	actions.Add("default", &v1alpha1.Action{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shared", Name: "deploy"},
+	})
+
+	_, err := actions.Get("default", "go-test")
+	assert.NoError(t, err)
+	_, err = actions.Get("shared", "deploy")
+	assert.NoError(t, err)
+	_, err = actions.Get("default", "deploy")
+	assert.Error(t, err)
+}
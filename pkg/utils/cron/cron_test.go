@@ -0,0 +1,101 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNext(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		shanghai = time.FixedZone("Asia/Shanghai", 8*3600)
+	}
+
+	cases := []struct {
+		desc     string
+		spec     string
+		now      time.Time
+		expected time.Time
+	}{
+		{
+			desc:     "every minute",
+			spec:     "* * * * *",
+			now:      time.Date(2020, 4, 25, 10, 30, 15, 0, time.UTC),
+			expected: time.Date(2020, 4, 25, 10, 31, 0, 0, time.UTC),
+		},
+		{
+			desc:     "exact time is not matched again",
+			spec:     "30 10 * * *",
+			now:      time.Date(2020, 4, 25, 10, 30, 0, 0, time.UTC),
+			expected: time.Date(2020, 4, 26, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			desc:     "step",
+			spec:     "*/15 * * * *",
+			now:      time.Date(2020, 4, 25, 10, 31, 0, 0, time.UTC),
+			expected: time.Date(2020, 4, 25, 10, 45, 0, 0, time.UTC),
+		},
+		{
+			desc:     "nightly in time zone",
+			spec:     "@daily",
+			now:      time.Date(2020, 4, 25, 10, 30, 0, 0, shanghai),
+			expected: time.Date(2020, 4, 26, 0, 0, 0, 0, shanghai),
+		},
+		{
+			desc:     "weekdays",
+			spec:     "0 2 * * mon-fri",
+			now:      time.Date(2020, 4, 24, 3, 0, 0, 0, time.UTC),
+			expected: time.Date(2020, 4, 27, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:     "sunday as 7",
+			spec:     "0 0 * * 7",
+			now:      time.Date(2020, 4, 25, 0, 0, 0, 0, time.UTC),
+			expected: time.Date(2020, 4, 26, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:     "day of month or day of week",
+			spec:     "0 0 1 * sun",
+			now:      time.Date(2020, 4, 27, 0, 0, 0, 0, time.UTC),
+			expected: time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:     "leap day",
+			spec:     "0 0 29 feb *",
+			now:      time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			expected: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc: "never",
+			spec: "0 0 30 2 *",
+			now:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, c := range cases {
+		s, err := Parse(c.spec)
+		if assert.NoError(t, err, c.desc) {
+			assert.True(t, c.expected.Equal(s.Next(c.now)), "%s: expected %v, got %v", c.desc, c.expected, s.Next(c.now))
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * * foo",
+		"*-5 * * * *",
+	} {
+		_, err := Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
@@ -2,4 +2,5 @@ package git
 
 // NewFakeGitRepo creates a repo to test git
 func NewFakeGitRepo() error {
+	return nil
 }
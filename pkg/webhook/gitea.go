@@ -0,0 +1,151 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+const (
+	giteaEventHeader     = "X-Gitea-Event"
+	giteaDeliveryHeader  = "X-Gitea-Delivery"
+	giteaSignatureHeader = "X-Gitea-Signature"
+
+	giteaPushEvent        = "push"
+	giteaPullRequestEvent = "pull_request"
+)
+
+// giteaPullRequestActions maps actions of Gitea pull request
+// which trigger events to normalized actions
+var giteaPullRequestActions = map[string]string{
+	"opened":       pullRequestOpened,
+	"synchronized": pullRequestSynchronized,
+	"reopened":     pullRequestReopened,
+}
+
+// gitea handles webhooks of Gitea
+type gitea struct{}
+
+// giteaPushPayload defines useful fields of Gitea push payload
+type giteaPushPayload struct {
+	Ref        string `json:"ref"`
+	Before     string `json:"before"`
+	After      string `json:"after"`
+	CompareURL string `json:"compare_url"`
+
+	Commits      []pushCommit `json:"commits"`
+	TotalCommits int          `json:"total_commits"`
+
+	Repository struct {
+		HTMLURL  string `json:"html_url"`
+		CloneURL string `json:"clone_url"`
+	} `json:"repository"`
+
+	Pusher struct {
+		Login string `json:"login"`
+	} `json:"pusher"`
+}
+
+// giteaPullRequestPayload defines useful fields of Gitea pull request payload
+type giteaPullRequestPayload struct {
+	Action string `json:"action"`
+	Number int    `json:"number"`
+
+	PullRequest struct {
+		HTMLURL string `json:"html_url"`
+		Head    struct {
+			Ref  string `json:"ref"`
+			SHA  string `json:"sha"`
+			Repo struct {
+				HTMLURL string `json:"html_url"`
+			} `json:"repo"`
+		} `json:"head"`
+		Base struct {
+			Ref string `json:"ref"`
+		} `json:"base"`
+	} `json:"pull_request"`
+
+	Repository struct {
+		HTMLURL  string `json:"html_url"`
+		CloneURL string `json:"clone_url"`
+	} `json:"repository"`
+
+	Sender struct {
+		Login string `json:"login"`
+	} `json:"sender"`
+}
+
+func (p *gitea) name() string {
+	return "gitea"
+}
+
+func (p *gitea) verify(header http.Header, body, secret []byte) error {
+	return verifyHMAC(header.Get(giteaSignatureHeader), body, secret)
+}
+
+func (p *gitea) delivery(header http.Header) string {
+	return header.Get(giteaDeliveryHeader)
+}
+
+func (p *gitea) parse(header http.Header, body []byte) (*v1alpha1.EventSpec, error) {
+	switch header.Get(giteaEventHeader) {
+	case giteaPushEvent:
+		return p.parsePush(body)
+	case giteaPullRequestEvent:
+		return p.parsePullRequest(body)
+	}
+	return nil, nil
+}
+
+func (p *gitea) parsePush(body []byte) (*v1alpha1.EventSpec, error) {
+	payload := giteaPushPayload{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+
+	return newPushEventSpec(
+		payload.Repository.HTMLURL,
+		payload.Ref,
+		payload.Before,
+		payload.After,
+		// old versions of Gitea don't send total commits
+		changedFiles(payload.Commits, payload.TotalCommits == 0 || payload.TotalCommits == len(payload.Commits)),
+		map[string]string{
+			v1alpha1.EventExtraPusher:     payload.Pusher.Login,
+			v1alpha1.EventExtraCompareURL: payload.CompareURL,
+			v1alpha1.EventExtraCloneURL:   payload.Repository.CloneURL,
+		},
+	), nil
+}
+
+func (p *gitea) parsePullRequest(body []byte) (*v1alpha1.EventSpec, error) {
+	payload := giteaPullRequestPayload{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+
+	action, ok := giteaPullRequestActions[payload.Action]
+	if !ok {
+		return nil, nil
+	}
+
+	return newPullRequestEventSpec(
+		&pullRequest{
+			repo:     payload.Repository.HTMLURL,
+			ref:      fmt.Sprintf("refs/pull/%d/head", payload.Number),
+			number:   payload.Number,
+			action:   action,
+			base:     payload.PullRequest.Base.Ref,
+			head:     payload.PullRequest.Head.Ref,
+			headRepo: payload.PullRequest.Head.Repo.HTMLURL,
+			commit:   payload.PullRequest.Head.SHA,
+		},
+		map[string]string{
+			v1alpha1.EventExtraPusher:     payload.Sender.Login,
+			v1alpha1.EventExtraCompareURL: payload.PullRequest.HTMLURL,
+			v1alpha1.EventExtraCloneURL:   payload.Repository.CloneURL,
+		},
+	), nil
+}
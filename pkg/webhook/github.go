@@ -0,0 +1,160 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+const (
+	githubEventHeader     = "X-GitHub-Event"
+	githubDeliveryHeader  = "X-GitHub-Delivery"
+	githubSignatureHeader = "X-Hub-Signature-256"
+
+	githubSignaturePrefix = "sha256="
+
+	// githubMaxCommits defines max number of commits in GitHub push payload
+	githubMaxCommits = 20
+
+	githubPushEvent        = "push"
+	githubPullRequestEvent = "pull_request"
+)
+
+// githubPullRequestActions maps actions of GitHub pull request
+// which trigger events to normalized actions
+var githubPullRequestActions = map[string]string{
+	"opened":      pullRequestOpened,
+	"synchronize": pullRequestSynchronized,
+	"reopened":    pullRequestReopened,
+}
+
+// github handles webhooks of GitHub
+type github struct{}
+
+// githubPushPayload defines useful fields of GitHub push payload
+type githubPushPayload struct {
+	Ref     string `json:"ref"`
+	Before  string `json:"before"`
+	After   string `json:"after"`
+	Compare string `json:"compare"`
+
+	Commits []pushCommit `json:"commits"`
+
+	Repository struct {
+		HTMLURL  string `json:"html_url"`
+		CloneURL string `json:"clone_url"`
+	} `json:"repository"`
+
+	Pusher struct {
+		Name string `json:"name"`
+	} `json:"pusher"`
+}
+
+// githubPullRequestPayload defines useful fields of GitHub pull request payload
+type githubPullRequestPayload struct {
+	Action string `json:"action"`
+	Number int    `json:"number"`
+
+	PullRequest struct {
+		HTMLURL string `json:"html_url"`
+		Head    struct {
+			Ref  string `json:"ref"`
+			SHA  string `json:"sha"`
+			Repo struct {
+				HTMLURL string `json:"html_url"`
+			} `json:"repo"`
+		} `json:"head"`
+		Base struct {
+			Ref string `json:"ref"`
+		} `json:"base"`
+	} `json:"pull_request"`
+
+	Repository struct {
+		HTMLURL  string `json:"html_url"`
+		CloneURL string `json:"clone_url"`
+	} `json:"repository"`
+
+	Sender struct {
+		Login string `json:"login"`
+	} `json:"sender"`
+}
+
+func (p *github) name() string {
+	return "github"
+}
+
+func (p *github) verify(header http.Header, body, secret []byte) error {
+	signature := header.Get(githubSignatureHeader)
+	if !strings.HasPrefix(signature, githubSignaturePrefix) {
+		return fmt.Errorf("signature of %s is not found", githubSignatureHeader)
+	}
+	return verifyHMAC(strings.TrimPrefix(signature, githubSignaturePrefix), body, secret)
+}
+
+func (p *github) delivery(header http.Header) string {
+	return header.Get(githubDeliveryHeader)
+}
+
+func (p *github) parse(header http.Header, body []byte) (*v1alpha1.EventSpec, error) {
+	switch header.Get(githubEventHeader) {
+	case githubPushEvent:
+		return p.parsePush(body)
+	case githubPullRequestEvent:
+		return p.parsePullRequest(body)
+	}
+	return nil, nil
+}
+
+func (p *github) parsePush(body []byte) (*v1alpha1.EventSpec, error) {
+	payload := githubPushPayload{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+
+	return newPushEventSpec(
+		payload.Repository.HTMLURL,
+		payload.Ref,
+		payload.Before,
+		payload.After,
+		// commits may be truncated if there are too many
+		changedFiles(payload.Commits, len(payload.Commits) < githubMaxCommits),
+		map[string]string{
+			v1alpha1.EventExtraPusher:     payload.Pusher.Name,
+			v1alpha1.EventExtraCompareURL: payload.Compare,
+			v1alpha1.EventExtraCloneURL:   payload.Repository.CloneURL,
+		},
+	), nil
+}
+
+func (p *github) parsePullRequest(body []byte) (*v1alpha1.EventSpec, error) {
+	payload := githubPullRequestPayload{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+
+	action, ok := githubPullRequestActions[payload.Action]
+	if !ok {
+		return nil, nil
+	}
+
+	return newPullRequestEventSpec(
+		&pullRequest{
+			repo:     payload.Repository.HTMLURL,
+			ref:      fmt.Sprintf("refs/pull/%d/head", payload.Number),
+			number:   payload.Number,
+			action:   action,
+			base:     payload.PullRequest.Base.Ref,
+			head:     payload.PullRequest.Head.Ref,
+			headRepo: payload.PullRequest.Head.Repo.HTMLURL,
+			commit:   payload.PullRequest.Head.SHA,
+		},
+		map[string]string{
+			v1alpha1.EventExtraPusher:     payload.Sender.Login,
+			v1alpha1.EventExtraCompareURL: payload.PullRequest.HTMLURL,
+			v1alpha1.EventExtraCloneURL:   payload.Repository.CloneURL,
+		},
+	), nil
+}
@@ -0,0 +1,169 @@
+package webhook
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+const (
+	gitlabEventHeader    = "X-Gitlab-Event"
+	gitlabDeliveryHeader = "X-Gitlab-Event-UUID"
+	gitlabTokenHeader    = "X-Gitlab-Token"
+
+	gitlabPushEvent         = "Push Hook"
+	gitlabTagEvent          = "Tag Push Hook"
+	gitlabMergeRequestEvent = "Merge Request Hook"
+)
+
+// gitlab handles webhooks of GitLab
+type gitlab struct{}
+
+// gitlabPushPayload defines useful fields of GitLab push and tag push payload
+type gitlabPushPayload struct {
+	Ref          string `json:"ref"`
+	Before       string `json:"before"`
+	After        string `json:"after"`
+	UserUsername string `json:"user_username"`
+
+	Commits           []pushCommit `json:"commits"`
+	TotalCommitsCount int          `json:"total_commits_count"`
+
+	Project struct {
+		WebURL     string `json:"web_url"`
+		GitHTTPURL string `json:"git_http_url"`
+	} `json:"project"`
+}
+
+// gitlabMergeRequestPayload defines useful fields of GitLab merge request payload
+type gitlabMergeRequestPayload struct {
+	User struct {
+		Username string `json:"username"`
+	} `json:"user"`
+
+	Project struct {
+		WebURL     string `json:"web_url"`
+		GitHTTPURL string `json:"git_http_url"`
+	} `json:"project"`
+
+	ObjectAttributes struct {
+		IID          int    `json:"iid"`
+		URL          string `json:"url"`
+		Action       string `json:"action"`
+		SourceBranch string `json:"source_branch"`
+		TargetBranch string `json:"target_branch"`
+		// OldRev is set only if new commits are pushed
+		OldRev     string `json:"oldrev"`
+		LastCommit struct {
+			ID string `json:"id"`
+		} `json:"last_commit"`
+		Source struct {
+			WebURL string `json:"web_url"`
+		} `json:"source"`
+	} `json:"object_attributes"`
+}
+
+func (p *gitlab) name() string {
+	return "gitlab"
+}
+
+// verify compares secret token of GitLab in constant time,
+// GitLab sends the secret token as it is instead of signing payload
+func (p *gitlab) verify(header http.Header, body, secret []byte) error {
+	token := header.Get(gitlabTokenHeader)
+	if token == "" {
+		return fmt.Errorf("token of %s is not found", gitlabTokenHeader)
+	}
+	if subtle.ConstantTimeCompare([]byte(token), secret) != 1 {
+		return fmt.Errorf("token is not matched")
+	}
+	return nil
+}
+
+func (p *gitlab) delivery(header http.Header) string {
+	return header.Get(gitlabDeliveryHeader)
+}
+
+func (p *gitlab) parse(header http.Header, body []byte) (*v1alpha1.EventSpec, error) {
+	switch header.Get(gitlabEventHeader) {
+	case gitlabPushEvent, gitlabTagEvent:
+		return p.parsePush(body)
+	case gitlabMergeRequestEvent:
+		return p.parseMergeRequest(body)
+	}
+	return nil, nil
+}
+
+func (p *gitlab) parsePush(body []byte) (*v1alpha1.EventSpec, error) {
+	payload := gitlabPushPayload{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+
+	compareURL := ""
+	if !isZeroCommit(payload.Before) && !isZeroCommit(payload.After) {
+		compareURL = fmt.Sprintf("%s/-/compare/%s...%s",
+			strings.TrimSuffix(payload.Project.WebURL, "/"), payload.Before, payload.After)
+	}
+
+	return newPushEventSpec(
+		payload.Project.WebURL,
+		payload.Ref,
+		payload.Before,
+		payload.After,
+		// GitLab only sends the latest 20 commits
+		changedFiles(payload.Commits, payload.TotalCommitsCount == len(payload.Commits)),
+		map[string]string{
+			v1alpha1.EventExtraPusher:     payload.UserUsername,
+			v1alpha1.EventExtraCompareURL: compareURL,
+			v1alpha1.EventExtraCloneURL:   payload.Project.GitHTTPURL,
+		},
+	), nil
+}
+
+func (p *gitlab) parseMergeRequest(body []byte) (*v1alpha1.EventSpec, error) {
+	payload := gitlabMergeRequestPayload{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+
+	attrs := &payload.ObjectAttributes
+
+	action := ""
+	switch attrs.Action {
+	case "open":
+		action = pullRequestOpened
+	case "reopen":
+		action = pullRequestReopened
+	case "update":
+		// update without oldrev means only title or description is changed
+		if attrs.OldRev == "" {
+			return nil, nil
+		}
+		action = pullRequestSynchronized
+	default:
+		return nil, nil
+	}
+
+	return newPullRequestEventSpec(
+		&pullRequest{
+			repo:     payload.Project.WebURL,
+			ref:      fmt.Sprintf("refs/merge-requests/%d/head", attrs.IID),
+			number:   attrs.IID,
+			action:   action,
+			base:     attrs.TargetBranch,
+			head:     attrs.SourceBranch,
+			headRepo: attrs.Source.WebURL,
+			commit:   attrs.LastCommit.ID,
+		},
+		map[string]string{
+			v1alpha1.EventExtraPusher:     payload.User.Username,
+			v1alpha1.EventExtraCompareURL: attrs.URL,
+			v1alpha1.EventExtraCloneURL:   payload.Project.GitHTTPURL,
+		},
+	), nil
+}
@@ -0,0 +1,168 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+)
+
+const (
+	branchRefPrefix = "refs/heads/"
+	tagRefPrefix    = "refs/tags/"
+
+	// pullRequestOpened means pull request is opened
+	pullRequestOpened = "opened"
+	// pullRequestSynchronized means new commits are pushed to pull request
+	pullRequestSynchronized = "synchronized"
+	// pullRequestReopened means pull request is reopened
+	pullRequestReopened = "reopened"
+)
+
+// verifyHMAC returns error if signature is not the hex encoded
+// hmac sha256 of body
+func verifyHMAC(signature string, body, secret []byte) error {
+	if signature == "" {
+		return fmt.Errorf("signature is not found")
+	}
+	actual, err := hex.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("signature is not hex encoded: %v", err)
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	// nolint: errcheck
+	mac.Write(body)
+	if !hmac.Equal(actual, mac.Sum(nil)) {
+		return fmt.Errorf("signature is not matched")
+	}
+	return nil
+}
+
+// whenOfRef returns when of event by pushed ref,
+// false will be returned if ref is neither a branch nor a tag
+func whenOfRef(ref string) (v1alpha1.When, bool) {
+	switch {
+	case strings.HasPrefix(ref, branchRefPrefix):
+		return v1alpha1.Push, true
+	case strings.HasPrefix(ref, tagRefPrefix):
+		return v1alpha1.Tag, true
+	}
+	return "", false
+}
+
+// isZeroCommit returns true if sha is empty or all zero,
+// which means ref is deleted
+func isZeroCommit(sha string) bool {
+	return strings.Trim(sha, "0") == ""
+}
+
+// pushCommit defines files changed by a pushed commit
+type pushCommit struct {
+	Added    []string `json:"added"`
+	Modified []string `json:"modified"`
+	Removed  []string `json:"removed"`
+}
+
+// changedFiles returns sorted and deduplicated files changed by commits,
+// nil will be returned if commits in payload are incomplete
+func changedFiles(commits []pushCommit, complete bool) []string {
+	if !complete {
+		return nil
+	}
+	set := map[string]struct{}{}
+	for i := range commits {
+		c := &commits[i]
+		for _, files := range [][]string{c.Added, c.Modified, c.Removed} {
+			for _, f := range files {
+				set[f] = struct{}{}
+			}
+		}
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	files := make([]string, 0, len(set))
+	for f := range set {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// newPushEventSpec returns spec of push event, nil will be returned
+// if ref is deleted or unsupported.
+// Changed files are ignored if ref is created because they are not compared with base
+func newPushEventSpec(repo, ref, before, after string, changed []string, extra map[string]string) *v1alpha1.EventSpec {
+	when, ok := whenOfRef(ref)
+	if !ok || isZeroCommit(after) {
+		return nil
+	}
+
+	spec := v1alpha1.EventSpec{
+		Repo: repo,
+		When: when,
+		Ref:  ref,
+		Extra: map[string]string{
+			v1alpha1.EventExtraCommit: after,
+		},
+	}
+	if !isZeroCommit(before) {
+		spec.Extra[v1alpha1.EventExtraBefore] = before
+		spec.ChangedFiles = changed
+	}
+	for k, v := range extra {
+		if v != "" {
+			spec.Extra[k] = v
+		}
+	}
+	return &spec
+}
+
+// pullRequest defines common fields of pull request from different providers
+type pullRequest struct {
+	// repo is url of base repo
+	repo string
+	// ref is ref of pull request head in base repo, e.g. refs/pull/1/head
+	ref string
+	// number is number of pull request
+	number int
+	// action is normalized action of pull request
+	action string
+	// base is name of base branch
+	base string
+	// head is name of head branch
+	head string
+	// headRepo is url of repo which head branch belongs to
+	headRepo string
+	// commit is sha of head commit
+	commit string
+}
+
+// newPullRequestEventSpec returns spec of pull request event
+func newPullRequestEventSpec(pr *pullRequest, extra map[string]string) *v1alpha1.EventSpec {
+	spec := v1alpha1.EventSpec{
+		Repo: pr.repo,
+		When: v1alpha1.PullRequest,
+		Ref:  pr.ref,
+		Extra: map[string]string{
+			v1alpha1.EventExtraCommit:      pr.commit,
+			v1alpha1.EventExtraPullRequest: strconv.Itoa(pr.number),
+			v1alpha1.EventExtraAction:      pr.action,
+			v1alpha1.EventExtraBaseRef:     branchRefPrefix + pr.base,
+			v1alpha1.EventExtraHeadRef:     branchRefPrefix + pr.head,
+			v1alpha1.EventExtraHeadRepo:    pr.headRepo,
+		},
+	}
+	for k, v := range extra {
+		if v != "" {
+			spec.Extra[k] = v
+		}
+	}
+	return &spec
+}
@@ -0,0 +1,271 @@
+// Package webhook defines a server which receives webhooks of git hosting
+// services and translates them into events
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/liubog2008/pkg/http/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	"github.com/liubog2008/oooops/pkg/client/clientset"
+	"github.com/liubog2008/oooops/pkg/utils/graceful"
+)
+
+const (
+	// maxPayloadSize defines max size of webhook payload
+	maxPayloadSize = 25 << 20
+)
+
+var (
+	// ErrNotFound defines error that provider of webhook is not supported
+	ErrNotFound = errors.MustNewFactory(http.StatusNotFound, "NotFound", "provider %{provider} is not supported")
+
+	// ErrMethodNotAllowed defines error that method of request is not POST
+	ErrMethodNotAllowed = errors.MustNewFactory(http.StatusMethodNotAllowed, "MethodNotAllowed", "method %{method} is not allowed")
+
+	// ErrUnauthorized defines error that signature of webhook is not right
+	ErrUnauthorized = errors.MustNewFactory(http.StatusUnauthorized, "Unauthorized", "unauthorized: %{err}")
+
+	// ErrBadRequest defines error that payload of webhook can't be parsed
+	ErrBadRequest = errors.MustNewFactory(http.StatusBadRequest, "BadRequest", "can't parse payload: %{err}")
+
+	// ErrFailedToCreate defines error that event can't be created
+	ErrFailedToCreate = errors.MustNewFactory(http.StatusInternalServerError, "FailedToCreate", "can't create event: %{err}")
+)
+
+// Interface defines interface to run webhook server
+type Interface interface {
+	Run(stopCh <-chan struct{}) error
+}
+
+// Config defines config to run webhook server
+type Config struct {
+	ExtClient clientset.Interface
+
+	// Namespace defines namespace of created events
+	Namespace string
+	// Secret defines secret shared with git hosting services to sign webhooks
+	Secret []byte
+
+	Addr                    string
+	GracefulShutdownTimeout time.Duration
+}
+
+// provider defines a git hosting service which sends webhooks
+type provider interface {
+	// name returns name of provider
+	name() string
+	// verify returns error if payload is not signed by secret
+	verify(header http.Header, body, secret []byte) error
+	// delivery returns unique id of the webhook delivery, it may be empty
+	delivery(header http.Header) string
+	// parse translates payload to event spec, nil will be returned
+	// if the webhook is ignored
+	parse(header http.Header, body []byte) (*v1alpha1.EventSpec, error)
+}
+
+type server struct {
+	extClient clientset.Interface
+
+	namespace string
+	secret    []byte
+
+	addr                    string
+	gracefulShutdownTimeout time.Duration
+
+	providers map[string]provider
+}
+
+// New returns a webhook server
+func New(c *Config) Interface {
+	return newServer(c)
+}
+
+func newServer(c *Config) *server {
+	s := server{
+		extClient:               c.ExtClient,
+		namespace:               c.Namespace,
+		secret:                  c.Secret,
+		addr:                    c.Addr,
+		gracefulShutdownTimeout: c.GracefulShutdownTimeout,
+		providers:               map[string]provider{},
+	}
+
+	for _, p := range []provider{&github{}, &gitlab{}, &gitea{}} {
+		s.providers[p.name()] = p
+	}
+
+	return &s
+}
+
+func (s *server) Run(stopCh <-chan struct{}) error {
+	router := http.NewServeMux()
+	router.HandleFunc("/healthz", s.health)
+	router.Handle("/", s)
+
+	srv := &http.Server{
+		Addr:         s.addr,
+		Handler:      router,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		IdleTimeout:  15 * time.Second,
+	}
+
+	g := graceful.New()
+
+	g.OnShutdown(func(ctx context.Context) {
+		if err := srv.Shutdown(ctx); err != nil {
+			klog.Errorf("Could not gracefully shutdown the server: %v", err)
+		}
+	})
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil {
+			klog.Infof("listen and serve finished: %v", err)
+		}
+	}()
+
+	g.WaitForShutdown(stopCh, s.gracefulShutdownTimeout)
+
+	return nil
+}
+
+// ServeHTTP handles webhooks, path of request is the name of provider
+// e.g. /github, /gitlab or /gitea
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(r.URL.Path, "/")
+	p, ok := s.providers[name]
+	if !ok {
+		writeError(w, ErrNotFound.New(name))
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeError(w, ErrMethodNotAllowed.New(r.Method))
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxPayloadSize))
+	if err != nil {
+		writeError(w, ErrBadRequest.New(err))
+		return
+	}
+
+	if err := p.verify(r.Header, body, s.secret); err != nil {
+		writeError(w, ErrUnauthorized.New(err))
+		return
+	}
+
+	spec, err := p.parse(r.Header, body)
+	if err != nil {
+		writeError(w, ErrBadRequest.New(err))
+		return
+	}
+
+	if spec == nil {
+		klog.V(4).Infof("ignore webhook from %s", name)
+		writeResponse(w, http.StatusOK, &response{Ignored: true})
+		return
+	}
+
+	event := v1alpha1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: s.namespace,
+		},
+		Spec: *spec,
+	}
+	if event.Spec.Extra == nil {
+		event.Spec.Extra = map[string]string{}
+	}
+	event.Spec.Extra[v1alpha1.EventExtraProvider] = name
+
+	// use delivery id as name to avoid creating duplicated events
+	// when webhook is redelivered
+	delivery := strings.ToLower(p.delivery(r.Header))
+	if delivery != "" && len(validation.IsDNS1123Subdomain(name+"-"+delivery)) == 0 {
+		event.Name = name + "-" + delivery
+	} else {
+		event.GenerateName = name + "-"
+	}
+
+	created, err := s.extClient.MarioV1alpha1().Events(s.namespace).Create(&event)
+	if err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			writeResponse(w, http.StatusOK, &response{Name: event.Name})
+			return
+		}
+		writeError(w, ErrFailedToCreate.New(err))
+		return
+	}
+
+	klog.Infof("event %s/%s is created by webhook from %s", created.Namespace, created.Name, name)
+
+	writeResponse(w, http.StatusCreated, &response{Name: created.Name})
+}
+
+func (s *server) health(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte("ok")); err != nil {
+		klog.Errorf("can't write response: %v", err)
+	}
+}
+
+// response defines response of webhook
+type response struct {
+	// Name defines name of created event
+	Name string `json:"name,omitempty"`
+	// Ignored means webhook is ignored and no event is created
+	Ignored bool `json:"ignored,omitempty"`
+}
+
+func writeResponse(w http.ResponseWriter, code int, resp *response) {
+	b, err := json.Marshal(resp)
+	if err != nil {
+		klog.Errorf("can't marshal response: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if _, err := w.Write(b); err != nil {
+		klog.Errorf("can't write whole response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	e := unwarp(err)
+	b, err := json.Marshal(e)
+	if err != nil {
+		klog.Errorf("can't marshal error: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.Code)
+	if _, err := w.Write(b); err != nil {
+		klog.Errorf("can't write whole response: %v", err)
+	}
+}
+
+func unwarp(err error) *errors.Error {
+	switch e := err.(type) {
+	case *errors.Error:
+		return e
+	default:
+		return &errors.Error{
+			Code:    http.StatusInternalServerError,
+			Reason:  "Unknown",
+			Message: err.Error(),
+		}
+	}
+}
@@ -0,0 +1,408 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/liubog2008/oooops/pkg/apis/mario/v1alpha1"
+	"github.com/liubog2008/oooops/pkg/client/clientset/fake"
+)
+
+const (
+	testNamespace = "test"
+	testSecret    = "It's a Secret to Everybody"
+)
+
+func sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestReplayFixtures(t *testing.T) {
+	cases := []struct {
+		desc     string
+		provider string
+		fixture  string
+		header   map[string]string
+		// sign sets signature or token of request
+		sign     func(h http.Header, body []byte)
+		code     int
+		name     string
+		expected *v1alpha1.EventSpec
+	}{
+		{
+			desc:     "github push",
+			provider: "github",
+			fixture:  "github-push.json",
+			header: map[string]string{
+				githubEventHeader:    "push",
+				githubDeliveryHeader: "72D3162E-CC78-11E3-81AB-4C9367DC0958",
+			},
+			sign: func(h http.Header, body []byte) {
+				h.Set(githubSignatureHeader, githubSignaturePrefix+sign(body))
+			},
+			code: http.StatusCreated,
+			name: "github-72d3162e-cc78-11e3-81ab-4c9367dc0958",
+			expected: &v1alpha1.EventSpec{
+				Repo: "https://github.com/liubog2008/oooops",
+				When: v1alpha1.Push,
+				Ref:  "refs/heads/master",
+				Extra: map[string]string{
+					v1alpha1.EventExtraProvider:   "github",
+					v1alpha1.EventExtraCommit:     "1481a2de7b2a7d02428ad93446ab166be7793fbb",
+					v1alpha1.EventExtraBefore:     "6113728f27ae82c7b1a177c8d03f9e96e0adf246",
+					v1alpha1.EventExtraPusher:     "liubog2008",
+					v1alpha1.EventExtraCompareURL: "https://github.com/liubog2008/oooops/compare/6113728f27ae...1481a2de7b2a",
+					v1alpha1.EventExtraCloneURL:   "https://github.com/liubog2008/oooops.git",
+				},
+				ChangedFiles: []string{"README.md"},
+			},
+		},
+		{
+			desc:     "github tag",
+			provider: "github",
+			fixture:  "github-tag.json",
+			header: map[string]string{
+				githubEventHeader:    "push",
+				githubDeliveryHeader: "8a1e4c2a-cc78-11e3-81ab-4c9367dc0958",
+			},
+			sign: func(h http.Header, body []byte) {
+				h.Set(githubSignatureHeader, githubSignaturePrefix+sign(body))
+			},
+			code: http.StatusCreated,
+			name: "github-8a1e4c2a-cc78-11e3-81ab-4c9367dc0958",
+			expected: &v1alpha1.EventSpec{
+				Repo: "https://github.com/liubog2008/oooops",
+				When: v1alpha1.Tag,
+				Ref:  "refs/tags/v1.0.0",
+				Extra: map[string]string{
+					v1alpha1.EventExtraProvider:   "github",
+					v1alpha1.EventExtraCommit:     "1481a2de7b2a7d02428ad93446ab166be7793fbb",
+					v1alpha1.EventExtraPusher:     "liubog2008",
+					v1alpha1.EventExtraCompareURL: "https://github.com/liubog2008/oooops/compare/v1.0.0",
+					v1alpha1.EventExtraCloneURL:   "https://github.com/liubog2008/oooops.git",
+				},
+			},
+		},
+		{
+			desc:     "github deleted branch is ignored",
+			provider: "github",
+			fixture:  "github-delete.json",
+			header: map[string]string{
+				githubEventHeader: "push",
+			},
+			sign: func(h http.Header, body []byte) {
+				h.Set(githubSignatureHeader, githubSignaturePrefix+sign(body))
+			},
+			code: http.StatusOK,
+		},
+		{
+			desc:     "github ping is ignored",
+			provider: "github",
+			fixture:  "github-push.json",
+			header: map[string]string{
+				githubEventHeader: "ping",
+			},
+			sign: func(h http.Header, body []byte) {
+				h.Set(githubSignatureHeader, githubSignaturePrefix+sign(body))
+			},
+			code: http.StatusOK,
+		},
+		{
+			desc:     "github bad signature",
+			provider: "github",
+			fixture:  "github-push.json",
+			header: map[string]string{
+				githubEventHeader: "push",
+			},
+			sign: func(h http.Header, body []byte) {
+				h.Set(githubSignatureHeader, githubSignaturePrefix+sign([]byte("other")))
+			},
+			code: http.StatusUnauthorized,
+		},
+		{
+			desc:     "github missing signature",
+			provider: "github",
+			fixture:  "github-push.json",
+			header: map[string]string{
+				githubEventHeader: "push",
+			},
+			sign: func(h http.Header, body []byte) {},
+			code: http.StatusUnauthorized,
+		},
+		{
+			desc:     "gitlab push",
+			provider: "gitlab",
+			fixture:  "gitlab-push.json",
+			header: map[string]string{
+				gitlabEventHeader:    gitlabPushEvent,
+				gitlabDeliveryHeader: "3f0d0e7c-6d1f-4b5e-9b5e-0c1a6a0f6a11",
+			},
+			sign: func(h http.Header, body []byte) {
+				h.Set(gitlabTokenHeader, testSecret)
+			},
+			code: http.StatusCreated,
+			name: "gitlab-3f0d0e7c-6d1f-4b5e-9b5e-0c1a6a0f6a11",
+			expected: &v1alpha1.EventSpec{
+				Repo: "https://gitlab.example.com/mike/diaspora",
+				When: v1alpha1.Push,
+				Ref:  "refs/heads/master",
+				Extra: map[string]string{
+					v1alpha1.EventExtraProvider: "gitlab",
+					v1alpha1.EventExtraCommit:   "da1560886d4f094c3e6c9ef40349f7d38b5d27d7",
+					v1alpha1.EventExtraBefore:   "95790bf891e76fee5e1747ab589903a6a1f80f22",
+					v1alpha1.EventExtraPusher:   "jsmith",
+					v1alpha1.EventExtraCompareURL: "https://gitlab.example.com/mike/diaspora/-/compare/" +
+						"95790bf891e76fee5e1747ab589903a6a1f80f22...da1560886d4f094c3e6c9ef40349f7d38b5d27d7",
+					v1alpha1.EventExtraCloneURL: "https://gitlab.example.com/mike/diaspora.git",
+				},
+				ChangedFiles: []string{"README.md"},
+			},
+		},
+		{
+			desc:     "gitlab tag",
+			provider: "gitlab",
+			fixture:  "gitlab-tag.json",
+			header: map[string]string{
+				gitlabEventHeader:    gitlabTagEvent,
+				gitlabDeliveryHeader: "5b7e1c4e-2f3a-4d9b-8c6e-1a2b3c4d5e6f",
+			},
+			sign: func(h http.Header, body []byte) {
+				h.Set(gitlabTokenHeader, testSecret)
+			},
+			code: http.StatusCreated,
+			name: "gitlab-5b7e1c4e-2f3a-4d9b-8c6e-1a2b3c4d5e6f",
+			expected: &v1alpha1.EventSpec{
+				Repo: "https://gitlab.example.com/jsmith/example",
+				When: v1alpha1.Tag,
+				Ref:  "refs/tags/v1.0.0",
+				Extra: map[string]string{
+					v1alpha1.EventExtraProvider: "gitlab",
+					v1alpha1.EventExtraCommit:   "82b3d5ae55f7080f1e6022629cdb57bfae7cccc7",
+					v1alpha1.EventExtraPusher:   "jsmith",
+					v1alpha1.EventExtraCloneURL: "https://gitlab.example.com/jsmith/example.git",
+				},
+			},
+		},
+		{
+			desc:     "gitlab bad token",
+			provider: "gitlab",
+			fixture:  "gitlab-push.json",
+			header: map[string]string{
+				gitlabEventHeader: gitlabPushEvent,
+			},
+			sign: func(h http.Header, body []byte) {
+				h.Set(gitlabTokenHeader, "wrong")
+			},
+			code: http.StatusUnauthorized,
+		},
+		{
+			desc:     "gitea push",
+			provider: "gitea",
+			fixture:  "gitea-push.json",
+			header: map[string]string{
+				giteaEventHeader:    "push",
+				giteaDeliveryHeader: "f6266f16-1bf3-46a5-9ea4-602e06ead473",
+			},
+			sign: func(h http.Header, body []byte) {
+				h.Set(giteaSignatureHeader, sign(body))
+			},
+			code: http.StatusCreated,
+			name: "gitea-f6266f16-1bf3-46a5-9ea4-602e06ead473",
+			expected: &v1alpha1.EventSpec{
+				Repo: "https://gitea.example.com/gitea/webhooks",
+				When: v1alpha1.Push,
+				Ref:  "refs/heads/develop",
+				Extra: map[string]string{
+					v1alpha1.EventExtraProvider: "gitea",
+					v1alpha1.EventExtraCommit:   "bffeb74224043ba2feb48d137756c8a9331c449a",
+					v1alpha1.EventExtraBefore:   "28e1879d029cb852e4844d9c718537df08844e03",
+					v1alpha1.EventExtraPusher:   "gitea",
+					v1alpha1.EventExtraCompareURL: "https://gitea.example.com/gitea/webhooks/compare/" +
+						"28e1879d029cb852e4844d9c718537df08844e03...bffeb74224043ba2feb48d137756c8a9331c449a",
+					v1alpha1.EventExtraCloneURL: "https://gitea.example.com/gitea/webhooks.git",
+				},
+				ChangedFiles: []string{"docs/webhooks.md", "services/api/main.go", "services/api/old.go"},
+			},
+		},
+		{
+			desc:     "github pull request",
+			provider: "github",
+			fixture:  "github-pull-request.json",
+			header: map[string]string{
+				githubEventHeader:    "pull_request",
+				githubDeliveryHeader: "9c1f2d3e-cc78-11e3-81ab-4c9367dc0958",
+			},
+			sign: func(h http.Header, body []byte) {
+				h.Set(githubSignatureHeader, githubSignaturePrefix+sign(body))
+			},
+			code: http.StatusCreated,
+			name: "github-9c1f2d3e-cc78-11e3-81ab-4c9367dc0958",
+			expected: &v1alpha1.EventSpec{
+				Repo: "https://github.com/liubog2008/oooops",
+				When: v1alpha1.PullRequest,
+				Ref:  "refs/pull/12/head",
+				Extra: map[string]string{
+					v1alpha1.EventExtraProvider:    "github",
+					v1alpha1.EventExtraCommit:      "34c5c7793cb3b279e22454cb6750c80560547b3a",
+					v1alpha1.EventExtraPullRequest: "12",
+					v1alpha1.EventExtraAction:      "synchronized",
+					v1alpha1.EventExtraBaseRef:     "refs/heads/master",
+					v1alpha1.EventExtraHeadRef:     "refs/heads/feature",
+					v1alpha1.EventExtraHeadRepo:    "https://github.com/octocat/oooops",
+					v1alpha1.EventExtraPusher:      "octocat",
+					v1alpha1.EventExtraCompareURL:  "https://github.com/liubog2008/oooops/pull/12",
+					v1alpha1.EventExtraCloneURL:    "https://github.com/liubog2008/oooops.git",
+				},
+			},
+		},
+		{
+			desc:     "gitlab merge request",
+			provider: "gitlab",
+			fixture:  "gitlab-merge-request.json",
+			header: map[string]string{
+				gitlabEventHeader:    gitlabMergeRequestEvent,
+				gitlabDeliveryHeader: "7a1b2c3d-6d1f-4b5e-9b5e-0c1a6a0f6a11",
+			},
+			sign: func(h http.Header, body []byte) {
+				h.Set(gitlabTokenHeader, testSecret)
+			},
+			code: http.StatusCreated,
+			name: "gitlab-7a1b2c3d-6d1f-4b5e-9b5e-0c1a6a0f6a11",
+			expected: &v1alpha1.EventSpec{
+				Repo: "http://example.com/gitlabhq/gitlab-test",
+				When: v1alpha1.PullRequest,
+				Ref:  "refs/merge-requests/1/head",
+				Extra: map[string]string{
+					v1alpha1.EventExtraProvider:    "gitlab",
+					v1alpha1.EventExtraCommit:      "da1560886d4f094c3e6c9ef40349f7d38b5d27d7",
+					v1alpha1.EventExtraPullRequest: "1",
+					v1alpha1.EventExtraAction:      "opened",
+					v1alpha1.EventExtraBaseRef:     "refs/heads/master",
+					v1alpha1.EventExtraHeadRef:     "refs/heads/ms-viewport",
+					v1alpha1.EventExtraHeadRepo:    "http://example.com/gitlabhq/gitlab-test",
+					v1alpha1.EventExtraPusher:      "root",
+					v1alpha1.EventExtraCompareURL:  "http://example.com/diaspora/merge_requests/1",
+					v1alpha1.EventExtraCloneURL:    "http://example.com/gitlabhq/gitlab-test.git",
+				},
+			},
+		},
+		{
+			desc:     "gitea pull request",
+			provider: "gitea",
+			fixture:  "gitea-pull-request.json",
+			header: map[string]string{
+				giteaEventHeader:    "pull_request",
+				giteaDeliveryHeader: "0b9c6a52-1bf3-46a5-9ea4-602e06ead473",
+			},
+			sign: func(h http.Header, body []byte) {
+				h.Set(giteaSignatureHeader, sign(body))
+			},
+			code: http.StatusCreated,
+			name: "gitea-0b9c6a52-1bf3-46a5-9ea4-602e06ead473",
+			expected: &v1alpha1.EventSpec{
+				Repo: "https://gitea.example.com/gitea/webhooks",
+				When: v1alpha1.PullRequest,
+				Ref:  "refs/pull/3/head",
+				Extra: map[string]string{
+					v1alpha1.EventExtraProvider:    "gitea",
+					v1alpha1.EventExtraCommit:      "bffeb74224043ba2feb48d137756c8a9331c449a",
+					v1alpha1.EventExtraPullRequest: "3",
+					v1alpha1.EventExtraAction:      "opened",
+					v1alpha1.EventExtraBaseRef:     "refs/heads/develop",
+					v1alpha1.EventExtraHeadRef:     "refs/heads/feature",
+					v1alpha1.EventExtraHeadRepo:    "https://gitea.example.com/gitea/webhooks",
+					v1alpha1.EventExtraPusher:      "gitea",
+					v1alpha1.EventExtraCompareURL:  "https://gitea.example.com/gitea/webhooks/pulls/3",
+					v1alpha1.EventExtraCloneURL:    "https://gitea.example.com/gitea/webhooks.git",
+				},
+			},
+		},
+		{
+			desc:     "github closed pull request is ignored",
+			provider: "github",
+			fixture:  "github-pull-request-closed.json",
+			header: map[string]string{
+				githubEventHeader: "pull_request",
+			},
+			sign: func(h http.Header, body []byte) {
+				h.Set(githubSignatureHeader, githubSignaturePrefix+sign(body))
+			},
+			code: http.StatusOK,
+		},
+	}
+
+	for _, c := range cases {
+		client := fake.NewSimpleClientset()
+		s := newServer(&Config{
+			ExtClient: client,
+			Namespace: testNamespace,
+			Secret:    []byte(testSecret),
+		})
+
+		body, err := ioutil.ReadFile(filepath.Join("testdata", c.fixture))
+		assert.NoError(t, err, c.desc)
+
+		req := httptest.NewRequest(http.MethodPost, "/"+c.provider, bytes.NewReader(body))
+		for k, v := range c.header {
+			req.Header.Set(k, v)
+		}
+		c.sign(req.Header, body)
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+
+		assert.Equal(t, c.code, w.Code, c.desc)
+
+		events, err := client.MarioV1alpha1().Events(testNamespace).List(metav1.ListOptions{})
+		assert.NoError(t, err, c.desc)
+
+		if c.expected == nil {
+			assert.Empty(t, events.Items, c.desc)
+			continue
+		}
+
+		if assert.Len(t, events.Items, 1, c.desc) {
+			assert.Equal(t, c.name, events.Items[0].Name, c.desc)
+			assert.Equal(t, *c.expected, events.Items[0].Spec, c.desc)
+		}
+
+		// redelivery will not create a new event
+		req = httptest.NewRequest(http.MethodPost, "/"+c.provider, bytes.NewReader(body))
+		for k, v := range c.header {
+			req.Header.Set(k, v)
+		}
+		c.sign(req.Header, body)
+
+		w = httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, c.desc)
+	}
+}
+
+func TestUnknownProvider(t *testing.T) {
+	s := newServer(&Config{
+		ExtClient: fake.NewSimpleClientset(),
+		Namespace: testNamespace,
+		Secret:    []byte(testSecret),
+	})
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/bitbucket", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/github", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}